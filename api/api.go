@@ -2,6 +2,9 @@ package api
 
 import (
 	"kortlink/internal/cache"
+	"kortlink/internal/config"
+	"kortlink/internal/geoip"
+	"kortlink/internal/threat"
 	"net/http"
 	"os"
 
@@ -19,27 +22,61 @@ import (
 // @BasePath /api/v1
 //@host kortlink-production.up.railway.app
 type APIServer struct {
-	addr   string
-	store  Store
-	logger zerolog.Logger
-	cache  *cache.RedisCache
+	addr     string
+	store    Store
+	logger   zerolog.Logger
+	cache    *cache.RedisCache
+	screener *threat.Screener
+	geo      *geoip.Resolver
 }
 
 func NewAPIServer(addr string, store Store) *APIServer {
 	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
 	redisCache := cache.NewRedisCache()
-	return &APIServer{addr: addr, store: store, logger: logger, cache: redisCache}
+	screener := threat.NewScreener(config.Envs.ThreatListPaths)
+	if screener.Enabled() {
+		if err := screener.Load(); err != nil {
+			logger.Error().Err(err).Msg("Failed to load threat lists")
+		}
+	}
+	geo := geoip.NewResolver(config.Envs.GeoIPDBPath)
+	if geo.Enabled() {
+		if err := geo.Load(); err != nil {
+			logger.Error().Err(err).Msg("Failed to load GeoIP database")
+		}
+	}
+	return &APIServer{addr: addr, store: store, logger: logger, cache: redisCache, screener: screener, geo: geo}
 }
 
 func (s *APIServer) Serve() {
 	router := gin.Default()
+	// Only trust forwarding headers from configured proxies, so clients
+	// cannot spoof their IP (and country) by sending X-Forwarded-For.
+	if err := router.SetTrustedProxies(config.Envs.TrustedProxies); err != nil {
+		s.logger.Error().Err(err).Msg("Invalid TRUSTED_PROXIES")
+	}
+	router.TrustedPlatform = config.Envs.TrustedPlatformHeader
 	apiV1 := router.Group("/api/v1")
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	//registering the routes
-	shortlinkService := NewShortlinkService(s.store, s.cache)
+	s.screener.Start(config.Envs.ThreatListRefreshInterval, nil)
+
+	metadataQueue := newMetadataQueue(s.store, s.cache)
+	metadataQueue.Start()
+	metadataQueue.StartBackfill(metadataBackfillInterval)
+	startTrashPurge(s.store, config.Envs.TrashRetention, config.Envs.TrashPurgeInterval)
+
+	shortlinkService := NewShortlinkService(s.store, s.cache, s.screener, s.geo, metadataQueue, newCookieSecret(config.Envs.LinkCookieSecret))
 	shortlinkService.ShortlinkRoutes(apiV1)
+	router.NoRoute(shortlinkService.PassthroughFallback(apiV1.BasePath()))
+	domainPolicyService := NewDomainPolicyService(s.store, s.cache)
+	domainPolicyService.DomainPolicyRoutes(apiV1)
+	tagService := NewTagService(s.store, s.cache)
+	tagService.TagRoutes(apiV1)
+	folderService := NewFolderService(s.store, s.cache)
+	folderService.FolderRoutes(apiV1)
 
 	s.logger.Info().Str("addr", s.addr).Msg("Starting API server")
 	if err := http.ListenAndServe(s.addr, router); err != nil {
@@ -0,0 +1,484 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"mime"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxBulkRows      = 10000
+	maxBulkBodyBytes = 32 << 20
+	// maxSlugAttempts bounds how often a row is retried with a new slug
+	// when the generated one is already taken.
+	maxSlugAttempts = 3
+	// maxBulkPasswords bounds the rows of one request that set a password.
+	// Each password is hashed with bcrypt's default cost, which takes around
+	// 100ms of CPU, so 10,000 of them would take minutes even spread over
+	// several cores.
+	maxBulkPasswords = 200
+)
+
+// csvColumns maps the accepted CSV header names to the link field they set.
+// Destinations that need nested values, such as rules or a split, can only
+// be created from JSON.
+var csvColumns = map[string]func(link *models.ShortURL, value string) error{
+	"original_url": func(link *models.ShortURL, value string) error {
+		link.OriginalURL = value
+		return nil
+	},
+	"password": func(link *models.ShortURL, value string) error {
+		link.Password = &value
+		return nil
+	},
+	"max_clicks": func(link *models.ShortURL, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("max_clicks must be a number")
+		}
+		link.MaxClicks = &n
+		return nil
+	},
+	"redirect_type": func(link *models.ShortURL, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("redirect_type must be a number")
+		}
+		link.RedirectType = n
+		return nil
+	},
+	"activate_at": func(link *models.ShortURL, value string) error {
+		return parseCSVTime(&link.ActivateAt, value, "activate_at")
+	},
+	"deactivate_at": func(link *models.ShortURL, value string) error {
+		return parseCSVTime(&link.DeactivateAt, value, "deactivate_at")
+	},
+	"inactive_url": func(link *models.ShortURL, value string) error {
+		link.InactiveURL = value
+		return nil
+	},
+	"interstitial": func(link *models.ShortURL, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("interstitial must be true or false")
+		}
+		link.Interstitial = &b
+		return nil
+	},
+	// Tags are separated by semicolons, as commas separate the columns.
+	"tags": func(link *models.ShortURL, value string) error {
+		link.Tags = strings.Split(value, ";")
+		return nil
+	},
+	"notes": func(link *models.ShortURL, value string) error {
+		link.Notes = &value
+		return nil
+	},
+	"folder_id": func(link *models.ShortURL, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("folder_id must be a number")
+		}
+		link.FolderID = &n
+		return nil
+	},
+	"utm_source":   func(link *models.ShortURL, value string) error { link.UTMSource = value; return nil },
+	"utm_medium":   func(link *models.ShortURL, value string) error { link.UTMMedium = value; return nil },
+	"utm_campaign": func(link *models.ShortURL, value string) error { link.UTMCampaign = value; return nil },
+	"utm_term":     func(link *models.ShortURL, value string) error { link.UTMTerm = value; return nil },
+	"utm_content":  func(link *models.ShortURL, value string) error { link.UTMContent = value; return nil },
+}
+
+func parseCSVTime(dst **time.Time, value, name string) error {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	*dst = &t
+	return nil
+}
+
+// bulkRow is one parsed input row; err is set when the row could not be
+// read into a payload.
+type bulkRow struct {
+	payload models.ShortURL
+	err     error
+}
+
+// readJSONRows reads a JSON array of create payloads. Each element is
+// decoded on its own, so a malformed row only fails that row.
+func readJSONRows(r io.Reader) ([]bulkRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.New("body must be a JSON array of links")
+	}
+	if len(raw) > maxBulkRows {
+		return nil, fmt.Errorf("at most %d links can be created at once", maxBulkRows)
+	}
+	rows := make([]bulkRow, len(raw))
+	for i, item := range raw {
+		if err := json.Unmarshal(item, &rows[i].payload); err != nil {
+			rows[i].err = errors.New("invalid link payload")
+		}
+	}
+	return rows, nil
+}
+
+// readCSVRows reads a CSV file whose first line names the columns, from the
+// keys of csvColumns. Empty cells leave the field unset.
+func readCSVRows(r io.Reader) ([]bulkRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV must start with a header line")
+	}
+	setters := make([]func(*models.ShortURL, string) error, len(header))
+	hasURL := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		setter, ok := csvColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		setters[i] = setter
+		hasURL = hasURL || name == "original_url"
+	}
+	if !hasURL {
+		return nil, errors.New("CSV must have an original_url column")
+	}
+
+	var rows []bulkRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if len(rows) == maxBulkRows {
+			return nil, fmt.Errorf("at most %d links can be created at once", maxBulkRows)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		var row bulkRow
+		if len(record) != len(header) {
+			row.err = fmt.Errorf("expected %d fields, got %d", len(header), len(record))
+		}
+		for i := 0; row.err == nil && i < len(record); i++ {
+			if value := strings.TrimSpace(record[i]); value != "" {
+				row.err = setters[i](&row.payload, value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readBulkRows reads the request body as a JSON array, a CSV body or a CSV
+// file uploaded in the "file" form field.
+func readBulkRows(c *gin.Context) ([]bulkRow, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+	switch mediaType {
+	case "application/json":
+		return readJSONRows(bytes.NewReader(body))
+	case "text/csv":
+		return readCSVRows(bytes.NewReader(body))
+	case "multipart/form-data":
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("upload the CSV file in the file field")
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readCSVRows(f)
+	default:
+		return nil, errors.New("Content-Type must be application/json, text/csv or multipart/form-data")
+	}
+}
+
+// @Summary      Create short URLs in bulk
+// @Description  Creates up to 10,000 short URLs from a JSON array of link payloads (as for POST /shortlink), a CSV body or a CSV file uploaded in the file field. CSV files start with a header naming the columns: original_url, password, max_clicks, redirect_type, activate_at, deactivate_at, inactive_url, interstitial, tags (separated by semicolons), folder_id, notes and utm_*. At most 200 rows per request can set a password. Every row is validated on its own and the valid rows are inserted in one transaction; the response has a result per row, with the created link or the row's error.
+// @Tags         shortlinks
+// @Accept       json
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        body  body      []models.ShortURLPayload  false  "Links to create"
+// @Param        file  formData  file                      false  "CSV file"
+// @Success      200   {object}  models.BulkCreateResponse
+// @Failure      400   {object}  models.Response
+// @Failure      413   {object}  models.Response
+// @Failure      500   {object}  models.Response
+// @Router       /api/v1/shortlinks/bulk [post]
+func (s *ShortlinkService) handleBulkCreateShortlinks(c *gin.Context) {
+	rows, err := readBulkRows(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utility.WriteJSON(c.Writer, http.StatusRequestEntityTooLarge, "Request body is too large", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	p, err := loadDomainPolicy(s.store)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to check domain policy", nil)
+		return
+	}
+	folderExists, err := knownFolders(s.store)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to check folders", nil)
+		return
+	}
+
+	response := models.BulkCreateResponse{Results: make([]models.BulkCreateResult, len(rows))}
+	var valid []int
+	passwords := 0
+	for i := range rows {
+		response.Results[i].Row = i + 1
+		err := rows[i].err
+		if err == nil {
+			err = s.validateNewLink(&rows[i].payload, p, folderExists)
+		}
+		if err == nil && rows[i].payload.Password != nil && *rows[i].payload.Password != "" {
+			if passwords++; passwords > maxBulkPasswords {
+				err = fmt.Errorf("at most %d rows per request can set a password", maxBulkPasswords)
+			}
+		}
+		if err != nil {
+			response.Results[i].Error = err.Error()
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	links, buildErrs := newShortLinks(rows, valid, time.Now())
+	var pending []int
+	for _, i := range valid {
+		if buildErrs[i] != nil {
+			response.Results[i].Error = buildErrs[i].Error()
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		batch := make([]*models.ShortURL, len(pending))
+		for j, i := range pending {
+			batch[j] = links[i]
+		}
+		rowErrs, err := s.store.CreateShortURLs(batch)
+		if err != nil {
+			utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to create short links", nil)
+			return
+		}
+		var taken []int
+		for j, i := range pending {
+			switch {
+			case rowErrs[j] == nil:
+				response.Results[i].Link = links[i]
+				s.metadata.Enqueue(links[i].ShortURL, links[i].OriginalURL)
+			case errors.Is(rowErrs[j], ErrShortURLTaken) && attempt < maxSlugAttempts:
+				links[i].ShortURL = utility.GenerateShortURL()
+				taken = append(taken, i)
+			default:
+				response.Results[i].Error = rowErrs[j].Error()
+			}
+		}
+		pending = taken
+	}
+
+	created := make([]linkChange, 0, len(response.Results))
+	for _, result := range response.Results {
+		if result.Link != nil {
+			created = append(created, linkChange{after: result.Link})
+			response.Created++
+		} else {
+			response.Failed++
+		}
+	}
+	recordHistory(s.store, c, models.LinkChangeCreate, nil, created...)
+	utility.WriteJSON(c.Writer, http.StatusOK, "Bulk create finished", response)
+}
+
+// newShortLinks builds the links for the rows at indexes, with their
+// passwords hashed on all CPUs at once. Links and errors are returned by row.
+func newShortLinks(rows []bulkRow, indexes []int, now time.Time) ([]*models.ShortURL, []error) {
+	links := make([]*models.ShortURL, len(rows))
+	errs := make([]error, len(rows))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for _, i := range indexes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			links[i], errs[i] = newShortLink(&rows[i].payload, now)
+		}(i)
+	}
+	wg.Wait()
+	return links, errs
+}
+
+// @Summary      Update or delete short URLs in bulk
+// @Description  Applies an action to every short URL matching the filter: delete, disable, enable (links disabled by this endpoint only), repoint to a new original_url, retag (replace the tags with tags) or move to to_folder_id (0 for no folder). Filter by a list of short_urls, a created_after/created_before range, utm_campaign, tag, folder_id and owner; set fields are combined and at least one is required. Links have no owner field: owner matches the X-Actor recorded when the link was created, so links created without one, or before history was kept, have no owner. The change is made in one statement, so it applies to all matching links or none, and their cache entries are cleared.
+// @Tags         shortlinks
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.BulkActionRequest  true  "Action and filter"
+// @Success      200   {object}  models.BulkActionResponse
+// @Failure      400   {object}  models.Response
+// @Failure      500   {object}  models.Response
+// @Router       /api/v1/shortlinks/bulk/actions [post]
+func (s *ShortlinkService) handleBulkAction(c *gin.Context) {
+	var payload models.BulkActionRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid request payload", nil)
+		return
+	}
+	if payload.Tag != "" {
+		tag, err := normalizeTag(payload.Tag)
+		if err != nil {
+			utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		payload.Tag = tag
+	}
+	// Actors are recorded trimmed, see requestActor.
+	payload.Owner = strings.TrimSpace(payload.Owner)
+	if err := validateLinkFilter(payload.LinkFilter); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	// The matching links are read first, so each one's history can show
+	// what the action changed.
+	before, err := s.store.GetShortURLs(payload.LinkFilter)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to update short links", nil)
+		return
+	}
+	var shortURLs []string
+	switch payload.Action {
+	case models.BulkActionDelete:
+		shortURLs, err = s.store.DeleteShortURLs(payload.LinkFilter)
+	case models.BulkActionDisable:
+		shortURLs, err = s.store.SetShortURLsDisabled(payload.LinkFilter, true, models.DisabledReasonManual)
+	case models.BulkActionEnable:
+		shortURLs, err = s.store.SetShortURLsDisabled(payload.LinkFilter, false, models.DisabledReasonManual)
+	case models.BulkActionRepoint:
+		if err := utility.ValidateUrlRequest(payload.OriginalURL); err != nil {
+			utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if !s.checkDestinations(c, payload.OriginalURL) {
+			return
+		}
+		_, utm, utmErr := utility.ApplyUTM(payload.OriginalURL, models.UTM{})
+		if utmErr != nil {
+			utility.WriteJSON(c.Writer, http.StatusBadRequest, utmErr.Error(), nil)
+			return
+		}
+		shortURLs, err = s.store.SetShortURLsOriginalURL(payload.LinkFilter, payload.OriginalURL, utm)
+	case models.BulkActionRetag:
+		tags, tagsErr := normalizeTags(payload.Tags)
+		if tagsErr != nil {
+			utility.WriteJSON(c.Writer, http.StatusBadRequest, tagsErr.Error(), nil)
+			return
+		}
+		shortURLs, err = s.store.SetShortURLsTags(payload.LinkFilter, tags)
+	case models.BulkActionMove:
+		if payload.ToFolderID == nil {
+			utility.WriteJSON(c.Writer, http.StatusBadRequest, "to_folder_id is required, 0 for no folder", nil)
+			return
+		}
+		if folderErr := checkFolder(s.store, payload.ToFolderID); folderErr != nil {
+			utility.WriteJSON(c.Writer, http.StatusBadRequest, folderErr.Error(), nil)
+			return
+		}
+		folderID := payload.ToFolderID
+		if *folderID == 0 {
+			folderID = nil
+		}
+		shortURLs, err = s.store.SetShortURLsFolder(payload.LinkFilter, folderID)
+	default:
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "action must be delete, disable, enable, repoint, retag or move", nil)
+		return
+	}
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to update short links", nil)
+		return
+	}
+
+	// The change bumped the links' versions, so history is recorded under
+	// them.
+	recordBumpedHistory(s.store, c, payload.Action, before, shortURLs)
+	keys := shortURLs
+	if payload.Action == models.BulkActionDelete {
+		keys = make([]string, 0, 2*len(shortURLs))
+		for _, shortURL := range shortURLs {
+			keys = append(keys, shortURL, rotationKey(shortURL))
+		}
+	}
+	_ = s.cache.DeleteKeys(keys)
+	if payload.Action == models.BulkActionRepoint {
+		for _, shortURL := range shortURLs {
+			s.metadata.Enqueue(shortURL, payload.OriginalURL)
+		}
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Bulk action applied", models.BulkActionResponse{
+		Action:    payload.Action,
+		Affected:  len(shortURLs),
+		ShortURLs: shortURLs,
+	})
+}
+
+// validateLinkFilter rejects empty filters, so a mistyped request cannot
+// act on every link.
+func validateLinkFilter(filter models.LinkFilter) error {
+	if len(filter.ShortURLs) > maxBulkRows {
+		return fmt.Errorf("at most %d short_urls can be given", maxBulkRows)
+	}
+	if filter.ShortURLs != nil && len(filter.ShortURLs) == 0 {
+		return errors.New("short_urls must not be empty")
+	}
+	if isEmptyLinkFilter(filter) {
+		return errors.New("short_urls or a filter is required")
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return errors.New("created_after must be before created_before")
+	}
+	return nil
+}
+
+// isEmptyLinkFilter reports whether filter selects links by nothing but
+// their trash state.
+func isEmptyLinkFilter(filter models.LinkFilter) bool {
+	return filter.ShortURLs == nil && filter.CreatedAfter == nil && filter.CreatedBefore == nil &&
+		filter.UTMCampaign == "" && filter.Tag == "" && filter.FolderID == nil && filter.Owner == ""
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"kortlink/internal/geoip"
+	"kortlink/internal/models"
+	"kortlink/internal/policy"
+	"kortlink/internal/useragent"
+	"kortlink/internal/utility"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// linkDestinations lists every URL a link can redirect to, so policy checks
+// cover targeted destinations and the inactive URL as well as the default
+// one.
+func linkDestinations(link *models.ShortURL) []string {
+	urls := []string{link.OriginalURL}
+	if link.InactiveURL != "" {
+		urls = append(urls, link.InactiveURL)
+	}
+	for _, rule := range link.Rules {
+		urls = append(urls, rule.URL)
+	}
+	for _, rule := range link.Targeting {
+		urls = append(urls, rule.URL)
+	}
+	if link.Split != nil {
+		for _, variant := range link.Split.Variants {
+			urls = append(urls, variant.URL)
+		}
+	}
+	if link.Rotation != nil {
+		urls = append(urls, link.Rotation.URLs...)
+	}
+	return urls
+}
+
+// checkDestinations runs the domain policy and threat screening over urls.
+// It writes the error response and returns false if any of them is rejected.
+func (s *ShortlinkService) checkDestinations(c *gin.Context, urls ...string) bool {
+	p, err := loadDomainPolicy(s.store)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to check domain policy", nil)
+		return false
+	}
+	if err := s.screenDestinations(p, urls...); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// screenDestinations returns an error for the first of urls that the domain
+// policy or threat screening rejects.
+func (s *ShortlinkService) screenDestinations(p *policy.DomainPolicy, urls ...string) error {
+	for _, url := range urls {
+		if err := p.Check(url); err != nil {
+			return err
+		}
+		if match, flagged := s.screener.Check(url); flagged {
+			if !match.Confirmed {
+				return errors.New("Destination matches a threat list entry for " + match.ThreatType)
+			}
+			return errors.New("Destination is flagged as " + match.ThreatType)
+		}
+	}
+	return nil
+}
+
+// visitor describes who is following a link, for targeting and click
+// analytics.
+type visitor struct {
+	useragent.Info
+	geoip.Location
+}
+
+// identifyVisitor parses the user agent and resolves the client IP, which
+// gin only takes from forwarding headers sent by trusted proxies.
+func (s *ShortlinkService) identifyVisitor(c *gin.Context) visitor {
+	location, _ := s.geo.Lookup(net.ParseIP(c.ClientIP()))
+	return visitor{Info: useragent.Parse(c.Request.UserAgent()), Location: location}
+}
+
+// selectDestination picks the URL this visitor is sent to, before
+// passthrough options are applied, and the split variant it belongs to, if
+// any. Rules are checked first, then targeting, then the split or rotation.
+func (s *ShortlinkService) selectDestination(c *gin.Context, link *models.ShortURL, v visitor) (string, string) {
+	if url, ok := s.matchRules(c, link, v); ok {
+		return url, ""
+	}
+	if len(link.Targeting) > 0 {
+		c.Header("Vary", "User-Agent")
+		if url, ok := matchTargeting(link.Targeting, v); ok {
+			return url, ""
+		}
+	}
+	if link.Split != nil {
+		variant := selectVariant(c, link.Split)
+		return variant.URL, variant.Name
+	}
+	if link.Rotation != nil {
+		return s.rotator.next(link.ShortURL, link.Rotation), ""
+	}
+	return link.OriginalURL, ""
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"errors"
+	"kortlink/internal/cache"
+	"kortlink/internal/models"
+	"kortlink/internal/policy"
+	"kortlink/internal/utility"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type DomainPolicyService struct {
+	store Store
+	cache *cache.RedisCache
+}
+
+func NewDomainPolicyService(s Store, c *cache.RedisCache) *DomainPolicyService {
+	return &DomainPolicyService{store: s, cache: c}
+}
+
+func (s *DomainPolicyService) DomainPolicyRoutes(r *gin.RouterGroup) {
+	r.GET("/admin/domain-policies", s.handleGetDomainPolicies)
+	r.POST("/admin/domain-policies", s.handleCreateDomainPolicy)
+	r.DELETE("/admin/domain-policies/:id", s.handleDeleteDomainPolicy)
+}
+
+// PolicyEnforcementResult reports how many existing links changed state after
+// the domain policy was re-evaluated.
+type PolicyEnforcementResult struct {
+	Disabled int `json:"disabled"`
+	Enabled  int `json:"enabled"`
+}
+
+// @Summary      List domain policies
+// @Description  Lists the allow and block entries checked against link destinations
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   models.DomainPolicy
+// @Failure      500  {object}  models.Response
+// @Router       /api/v1/admin/domain-policies [get]
+func (s *DomainPolicyService) handleGetDomainPolicies(c *gin.Context) {
+	entries, err := s.store.GetDomainPolicies()
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch domain policies", nil)
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Successfully fetched domain policies", entries)
+}
+
+// @Summary      Create domain policy
+// @Description  Adds an exact, wildcard-subdomain or regex allow/block entry and disables existing links that now violate the policy
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.DomainPolicyPayload  true  "Domain policy entry"
+// @Success      201   {object}  models.DomainPolicy
+// @Failure      400   {object}  models.Response
+// @Failure      500   {object}  models.Response
+// @Router       /api/v1/admin/domain-policies [post]
+func (s *DomainPolicyService) handleCreateDomainPolicy(c *gin.Context) {
+	var payload models.DomainPolicyPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid request payload", nil)
+		return
+	}
+
+	entry := &models.DomainPolicy{
+		Pattern:   payload.Pattern,
+		MatchType: payload.MatchType,
+		Action:    payload.Action,
+		Note:      payload.Note,
+	}
+	if err := policy.ValidateEntry(*entry); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := s.store.CreateDomainPolicy(entry); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to create domain policy", nil)
+		return
+	}
+
+	result, err := s.enforce(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Domain policy created but failed to re-evaluate links", nil)
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusCreated, "Domain policy created successfully", gin.H{
+		"policy":      entry,
+		"enforcement": result,
+	})
+}
+
+// @Summary      Delete domain policy
+// @Description  Removes a domain policy entry and re-enables links that no longer violate the policy
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Domain policy ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Failure      500  {object}  models.Response
+// @Router       /api/v1/admin/domain-policies/{id} [delete]
+func (s *DomainPolicyService) handleDeleteDomainPolicy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid domain policy ID", nil)
+		return
+	}
+
+	if err := s.store.DeleteDomainPolicy(id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Domain policy not found", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to delete domain policy", nil)
+		return
+	}
+
+	result, err := s.enforce(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Domain policy deleted but failed to re-evaluate links", nil)
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Domain policy deleted successfully", gin.H{
+		"enforcement": result,
+	})
+}
+
+// enforce re-evaluates every stored link against the current policy. Links
+// that now violate it are disabled and evicted from the cache; links that were
+// disabled by the policy but pass again are re-enabled. Both are recorded in
+// the links' history as made by the caller changing the policy.
+func (s *DomainPolicyService) enforce(c *gin.Context) (*PolicyEnforcementResult, error) {
+	p, err := loadDomainPolicy(s.store)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := s.store.GetAllShortURLs()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PolicyEnforcementResult{}
+	var changes []linkChange
+	// Cached links are evicted once their new version is recorded, so a
+	// redirect in between cannot cache the link with its old version.
+	defer func() {
+		recordHistory(s.store, c, models.LinkChangeDomainPolicy, nil, changes...)
+		for _, change := range changes {
+			_ = s.cache.Delete(change.after.ShortURL)
+		}
+	}()
+	for _, url := range urls {
+		violates := false
+		for _, destination := range linkDestinations(&url) {
+			if p.Check(destination) != nil {
+				violates = true
+				break
+			}
+		}
+		switch {
+		case violates && !url.Disabled:
+			version, err := s.store.SetShortURLDisabled(url.ShortURL, true, models.DisabledReasonDomainPolicy)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, policyChange(url, true, models.DisabledReasonDomainPolicy, version))
+			result.Disabled++
+		case !violates && url.Disabled && url.DisabledReason == models.DisabledReasonDomainPolicy:
+			version, err := s.store.SetShortURLDisabled(url.ShortURL, false, "")
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, policyChange(url, false, "", version))
+			result.Enabled++
+		}
+	}
+
+	log.Info().
+		Int("disabled", result.Disabled).
+		Int("enabled", result.Enabled).
+		Msg("Domain policy enforced")
+	return result, nil
+}
+
+// policyChange is a link disabled or enabled by the domain policy, for its
+// history under the version the change took.
+func policyChange(url models.ShortURL, disabled bool, reason string, version int) linkChange {
+	before, after := url, url
+	after.Disabled, after.DisabledReason, after.Version = disabled, reason, version
+	return linkChange{before: &before, after: &after, version: version}
+}
+
+func loadDomainPolicy(store Store) (*policy.DomainPolicy, error) {
+	entries, err := store.GetDomainPolicies()
+	if err != nil {
+		return nil, err
+	}
+	return policy.NewDomainPolicy(entries)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// linkETag is the entity tag of a link: its version, as a strong tag.
+func linkETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setLinkETag(c *gin.Context, link *models.ShortURL) {
+	c.Header("ETag", linkETag(link.Version))
+}
+
+// ifMatchVersion reads the link version a change is based on from the
+// If-Match header. check is false for "*", which matches any version, and
+// for a missing header when it is not required. It returns ok false, with
+// the response written, when the header is missing but required or is not
+// a single version tag.
+func ifMatchVersion(c *gin.Context, required bool) (version int, check bool, ok bool) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	switch {
+	case value == "" && required:
+		utility.WriteJSON(c.Writer, http.StatusPreconditionRequired, "If-Match header with the link's ETag is required", nil)
+		return 0, false, false
+	case value == "" || value == "*":
+		return 0, false, true
+	}
+	tag, quoted := strings.CutPrefix(value, `"`)
+	tag, closed := strings.CutSuffix(tag, `"`)
+	version, err := strconv.Atoi(tag)
+	if !quoted || !closed || err != nil || version < 0 {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, `If-Match must be "*" or a single ETag returned for the link`, nil)
+		return 0, false, false
+	}
+	return version, true, true
+}
+
+// preconditionFailed answers a change based on an outdated version with 412
+// and the current ETag, so the client can fetch the link again.
+func preconditionFailed(c *gin.Context, current int) {
+	c.Header("ETag", linkETag(current))
+	utility.WriteJSON(c.Writer, http.StatusPreconditionFailed, "Short URL has been changed since the version in If-Match", nil)
+}
+
+// claimVersion takes the next version of a link for a change based on
+// expected. It writes the response and returns false when the link does
+// not exist or has been changed since.
+func (s *ShortlinkService) claimVersion(c *gin.Context, shortURL string, expected int) (int, bool) {
+	version, err := s.store.ClaimShortURLVersion(shortURL, expected)
+	switch {
+	case errors.Is(err, ErrVersionMismatch):
+		preconditionFailed(c, version)
+		return 0, false
+	case errors.Is(err, pgx.ErrNoRows):
+		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
+		return 0, false
+	case err != nil:
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to check the short URL version", nil)
+		return 0, false
+	}
+	return version, true
+}
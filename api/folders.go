@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"kortlink/internal/cache"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxFolderNameLength = 100
+
+func validateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("folder name is required")
+	}
+	if len([]rune(name)) > maxFolderNameLength {
+		return "", fmt.Errorf("folder name must be at most %d characters", maxFolderNameLength)
+	}
+	return name, nil
+}
+
+type FolderService struct {
+	store Store
+	cache *cache.RedisCache
+}
+
+func NewFolderService(s Store, c *cache.RedisCache) *FolderService {
+	return &FolderService{store: s, cache: c}
+}
+
+func (s *FolderService) FolderRoutes(r *gin.RouterGroup) {
+	r.GET("/folders", s.handleGetFolders)
+	r.POST("/folders", s.handleCreateFolder)
+	r.PUT("/folders/:id", s.handleRenameFolder)
+	r.DELETE("/folders/:id", s.handleDeleteFolder)
+}
+
+// @Summary      List folders
+// @Description  Lists folders with the number of links filed in each and their combined access count
+// @Tags         folders
+// @Produce      json
+// @Success      200  {array}   models.Folder
+// @Failure      500  {object}  models.Response
+// @Router       /api/v1/folders [get]
+func (s *FolderService) handleGetFolders(c *gin.Context) {
+	folders, err := s.store.GetFolders()
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch folders", nil)
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Successfully fetched folders", folders)
+}
+
+// @Summary      Create folder
+// @Description  Creates a folder to file links in. Folder names are unique.
+// @Tags         folders
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.FolderPayload  true  "Folder"
+// @Success      201   {object}  models.Folder
+// @Failure      400   {object}  models.Response
+// @Failure      409   {object}  models.Response
+// @Failure      500   {object}  models.Response
+// @Router       /api/v1/folders [post]
+func (s *FolderService) handleCreateFolder(c *gin.Context) {
+	var payload models.FolderPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid request payload", nil)
+		return
+	}
+	name, err := validateFolderName(payload.Name)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	folder := &models.Folder{Name: name}
+	if err := s.store.CreateFolder(folder); err != nil {
+		if isUniqueViolation(err) {
+			utility.WriteJSON(c.Writer, http.StatusConflict, "A folder with this name already exists", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to create folder", nil)
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusCreated, "Folder created successfully", folder)
+}
+
+// @Summary      Rename folder
+// @Tags         folders
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                   true  "Folder ID"
+// @Param        body  body      models.FolderPayload  true  "New name"
+// @Success      200   {object}  models.Response
+// @Failure      400   {object}  models.Response
+// @Failure      404   {object}  models.Response
+// @Failure      409   {object}  models.Response
+// @Failure      500   {object}  models.Response
+// @Router       /api/v1/folders/{id} [put]
+func (s *FolderService) handleRenameFolder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid folder ID", nil)
+		return
+	}
+	var payload models.FolderPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid request payload", nil)
+		return
+	}
+	name, err := validateFolderName(payload.Name)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := s.store.RenameFolder(id, name); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Folder not found", nil)
+		case isUniqueViolation(err):
+			utility.WriteJSON(c.Writer, http.StatusConflict, "A folder with this name already exists", nil)
+		default:
+			utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to rename folder", nil)
+		}
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Folder renamed successfully", nil)
+}
+
+// @Summary      Delete folder
+// @Description  Deletes a folder. Its links are kept and no longer filed in a folder; each of them gets a new version, recorded in its history.
+// @Tags         folders
+// @Produce      json
+// @Param        id   path      int  true  "Folder ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Failure      500  {object}  models.Response
+// @Router       /api/v1/folders/{id} [delete]
+func (s *FolderService) handleDeleteFolder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid folder ID", nil)
+		return
+	}
+
+	before, err := allLinks(s.store, models.LinkFilter{FolderID: &id})
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to delete folder", nil)
+		return
+	}
+	shortURLs, err := s.store.DeleteFolder(id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Folder not found", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to delete folder", nil)
+		return
+	}
+	recordBumpedHistory(s.store, c, models.LinkChangeFolder, before, shortURLs)
+	_ = s.cache.DeleteKeys(shortURLs)
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Folder deleted successfully", nil)
+}
@@ -0,0 +1,389 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// actorHeader names who is making a change, for the link history. Kortlink
+// has no accounts of its own, so it is expected to be set by an
+// authenticating proxy or the calling application.
+const (
+	actorHeader    = "X-Actor"
+	maxActorLength = 200
+)
+
+// requestActor returns who made the request and the address it came from.
+func requestActor(c *gin.Context) (string, string) {
+	actor := strings.TrimSpace(c.GetHeader(actorHeader))
+	if runes := []rune(actor); len(runes) > maxActorLength {
+		actor = string(runes[:maxActorLength])
+	}
+	return actor, c.ClientIP()
+}
+
+// linkState returns the part of link that its history records.
+func linkState(link *models.ShortURL) models.LinkState {
+	state := models.LinkState{
+		OriginalURL:    link.OriginalURL,
+		UTM:            link.UTM,
+		PasswordHash:   link.PasswordHash,
+		RedirectType:   link.RedirectType,
+		Passthrough:    link.Passthrough,
+		Rules:          link.Rules,
+		Targeting:      link.Targeting,
+		Split:          link.Split,
+		Rotation:       link.Rotation,
+		Interstitial:   showsInterstitial(link),
+		OpenGraph:      link.OpenGraph,
+		MaxClicks:      link.MaxClicks,
+		ActivateAt:     link.ActivateAt,
+		DeactivateAt:   link.DeactivateAt,
+		InactiveURL:    link.InactiveURL,
+		Tags:           link.Tags,
+		FolderID:       link.FolderID,
+		Notes:          notesText(link),
+		Disabled:       link.Disabled,
+		DisabledReason: link.DisabledReason,
+	}
+	if len(state.Tags) == 0 {
+		state.Tags = nil
+	}
+	return state
+}
+
+// diffStates lists the fields that differ between two states. Password
+// hashes are not exposed: a changed password is reported as "password",
+// with whether the link was protected before and after.
+func diffStates(before, after models.LinkState) (map[string]models.FieldChange, error) {
+	oldFields, err := stateFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := stateFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]models.FieldChange)
+	for name := range oldFields {
+		if _, ok := newFields[name]; !ok {
+			newFields[name] = nil
+		}
+	}
+	for name, value := range newFields {
+		if bytes.Equal(oldFields[name], value) || name == "password_hash" {
+			continue
+		}
+		var change models.FieldChange
+		if oldFields[name] != nil {
+			if err := json.Unmarshal(oldFields[name], &change.Old); err != nil {
+				return nil, err
+			}
+		}
+		if value != nil {
+			if err := json.Unmarshal(value, &change.New); err != nil {
+				return nil, err
+			}
+		}
+		changes[name] = change
+	}
+
+	if before.PasswordHash != after.PasswordHash {
+		changes["password"] = models.FieldChange{Old: before.PasswordHash != "", New: after.PasswordHash != ""}
+	}
+	return changes, nil
+}
+
+func stateFields(state models.LinkState) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	return fields, json.Unmarshal(data, &fields)
+}
+
+// withState returns a copy of link with the configuration in state and the
+// given version, as SetShortURLState leaves it.
+func withState(link *models.ShortURL, state models.LinkState, version int) *models.ShortURL {
+	updated := *link
+	updated.Version = version
+	updated.OriginalURL = state.OriginalURL
+	updated.UTM = state.UTM
+	updated.PasswordHash = state.PasswordHash
+	updated.PasswordProtected = state.PasswordHash != ""
+	updated.RedirectType = state.RedirectType
+	updated.Passthrough = state.Passthrough
+	updated.Rules = state.Rules
+	updated.Targeting = state.Targeting
+	updated.Split = state.Split
+	updated.Rotation = state.Rotation
+	updated.Interstitial = &state.Interstitial
+	updated.OpenGraph = state.OpenGraph
+	updated.MaxClicks = state.MaxClicks
+	updated.ActivateAt = state.ActivateAt
+	updated.DeactivateAt = state.DeactivateAt
+	updated.InactiveURL = state.InactiveURL
+	updated.Tags = state.Tags
+	updated.FolderID = state.FolderID
+	updated.Notes = &state.Notes
+	updated.Disabled = state.Disabled
+	updated.DisabledReason = state.DisabledReason
+	return &updated
+}
+
+// saveLinkState writes state over the configuration of existing, checking
+// that the link is still at version expected when check is set, and
+// records the change in its history. The write and the version bump are one
+// transaction, so a failed write changes nothing and a successful one is
+// always recorded. It writes the error response and returns false when the
+// link does not exist, has been changed since or could not be saved.
+func (s *ShortlinkService) saveLinkState(c *gin.Context, existing *models.ShortURL, state models.LinkState, expected int, check bool, action string, rolledBackTo *int) (*models.ShortURL, bool) {
+	shortURL := existing.ShortURL
+	var expectedVersion *int
+	if check {
+		expectedVersion = &expected
+	}
+	version, err := s.store.SetShortURLState(shortURL, state, expectedVersion)
+	switch {
+	case errors.Is(err, ErrVersionMismatch):
+		preconditionFailed(c, version)
+		return nil, false
+	case errors.Is(err, pgx.ErrNoRows):
+		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
+		return nil, false
+	case err != nil:
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to save short URL", nil)
+		return nil, false
+	}
+
+	updated, err := s.store.GetShortURL(shortURL)
+	if err != nil {
+		log.Error().Err(err).Str("short_url", shortURL).Msg("Failed to read back saved link")
+		updated = withState(existing, state, version)
+	}
+	// History is recorded before the cache is cleared, so a link cached in
+	// between does not keep its old version.
+	recordHistory(s.store, c, action, rolledBackTo, linkChange{before: existing, after: updated, version: version})
+	_ = s.cache.Delete(shortURL)
+	if state.OriginalURL != existing.OriginalURL {
+		s.metadata.Enqueue(shortURL, state.OriginalURL)
+	}
+	setLinkETag(c, updated)
+	return updated, true
+}
+
+// linkChange is a link before and after a change. before is nil for links
+// that were just created; for delete and restore both are the same link.
+// version is the version claimed for the change with If-Match, or 0.
+type linkChange struct {
+	before  *models.ShortURL
+	after   *models.ShortURL
+	version int
+}
+
+// recordHistory adds a version to the history of each changed link and sets
+// the new version on the after link. Changes that left every field as it
+// was are skipped unless action is one that is always recorded or a version
+// was claimed for them. The change has already been made, so failures are
+// logged rather than returned.
+func recordHistory(store Store, c *gin.Context, action string, rolledBackTo *int, changes ...linkChange) {
+	actor, ip := requestActor(c)
+	versions := make([]*models.LinkVersion, 0, len(changes))
+	links := make([]*models.ShortURL, 0, len(changes))
+	for _, change := range changes {
+		var before models.LinkState
+		if change.before != nil {
+			before = linkState(change.before)
+		}
+		after := linkState(change.after)
+		fields, err := diffStates(before, after)
+		if err != nil {
+			log.Error().Err(err).Str("short_url", change.after.ShortURL).Msg("Failed to compare link versions")
+			continue
+		}
+		if len(fields) == 0 && change.version == 0 && action != models.LinkChangeCreate && action != models.LinkChangeDelete &&
+			action != models.LinkChangeRestore && action != models.LinkChangeRollback {
+			continue
+		}
+		versions = append(versions, &models.LinkVersion{
+			Version:      change.version,
+			ShortURL:     change.after.ShortURL,
+			Action:       action,
+			Actor:        actor,
+			IP:           ip,
+			Changes:      fields,
+			RolledBackTo: rolledBackTo,
+			State:        after,
+		})
+		links = append(links, change.after)
+	}
+	if len(versions) == 0 {
+		return
+	}
+	claimed := make([]int, len(versions))
+	for i, v := range versions {
+		claimed[i] = v.Version
+	}
+	err := store.AddShortURLVersions(versions)
+	if err != nil {
+		log.Error().Err(err).Str("action", action).Int("links", len(versions)).Msg("Failed to record link history")
+	}
+	// A claimed version is the link's version even if recording failed.
+	for i, v := range versions {
+		switch {
+		case claimed[i] != 0:
+			links[i].Version = claimed[i]
+		case err == nil && v.Version != 0:
+			links[i].Version = v.Version
+		}
+	}
+}
+
+// changedLinks pairs the links a bulk change touched, as they are now, with
+// how they were before it. Links missing from before are skipped.
+func changedLinks(before []models.ShortURL, after []models.ShortURL) []linkChange {
+	previous := make(map[string]*models.ShortURL, len(before))
+	for i := range before {
+		previous[before[i].ShortURL] = &before[i]
+	}
+	changes := make([]linkChange, 0, len(after))
+	for i := range after {
+		if link, ok := previous[after[i].ShortURL]; ok {
+			changes = append(changes, linkChange{before: link, after: &after[i]})
+		}
+	}
+	return changes
+}
+
+// allLinks reads the links matching filter, whether they are in the trash
+// or not.
+func allLinks(store Store, filter models.LinkFilter) ([]models.ShortURL, error) {
+	links, err := store.GetShortURLs(filter)
+	if err != nil {
+		return nil, err
+	}
+	filter.Deleted = true
+	trashed, err := store.GetShortURLs(filter)
+	if err != nil {
+		return nil, err
+	}
+	return append(links, trashed...), nil
+}
+
+// recordBumpedHistory records a change the store made to shortURLs, whose
+// versions it bumped in the same transaction, with before holding the links
+// as they were. Each entry is filed under the version the store took.
+func recordBumpedHistory(store Store, c *gin.Context, action string, before []models.ShortURL, shortURLs []string) {
+	if len(shortURLs) == 0 {
+		return
+	}
+	after, err := allLinks(store, models.LinkFilter{ShortURLs: shortURLs})
+	if err != nil {
+		log.Error().Err(err).Str("action", action).Msg("Failed to read links for their history")
+		return
+	}
+	changes := changedLinks(before, after)
+	for i := range changes {
+		changes[i].version = changes[i].after.Version
+	}
+	recordHistory(store, c, action, nil, changes...)
+}
+
+// @Summary      Get the history of a short URL
+// @Description  Lists every recorded change to a short URL, newest first: its version, what was done, who did it (from the X-Actor header) and from where, and the old and new value of each field that changed. Passwords are never shown; a change is reported as password, with whether the link was protected before and after.
+// @Tags         shortlinks
+// @Produce      json
+// @Param        shortURL   path      string  true  "Short URL"
+// @Success      200        {array}   models.LinkVersion
+// @Failure      404        {object}  models.Response
+// @Failure      500        {object}  models.Response
+// @Router       /api/v1/{shortURL}/history [get]
+func (s *ShortlinkService) handleGetHistory(c *gin.Context) {
+	shortURL := c.Param("shortURL")
+	if _, err := s.store.GetShortURL(shortURL); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
+		return
+	}
+	versions, err := s.store.GetShortURLVersions(shortURL)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch history", nil)
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Successfully fetched history", versions)
+}
+
+// @Summary      Roll a short URL back to an earlier version
+// @Description  Restores the configuration a short URL had at a version of its history: destination, password, rules, schedule, tags, folder, notes and the other editable settings. Access counts and clicks are kept. The rollback is recorded as a new version. Destinations are checked against the current domain policy and threat lists, and a folder deleted since leaves the link unfiled.
+// @Tags         shortlinks
+// @Accept       json
+// @Produce      json
+// @Param        shortURL   path      string                  true  "Short URL"
+// @Param        If-Match   header    string                  false "ETag of the version the rollback is based on; 412 if the link has changed since"
+// @Param        body       body      models.RollbackPayload  true  "Version to roll back to"
+// @Success      200        {object}  models.ShortURL
+// @Failure      400        {object}  models.Response
+// @Failure      404        {object}  models.Response
+// @Failure      412        {object}  models.Response
+// @Failure      500        {object}  models.Response
+// @Router       /api/v1/{shortURL}/rollback [post]
+func (s *ShortlinkService) handleRollbackShortlink(c *gin.Context) {
+	shortURL := c.Param("shortURL")
+	expected, checkVersion, ok := ifMatchVersion(c, false)
+	if !ok {
+		return
+	}
+	existing, err := s.store.GetShortURL(shortURL)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
+		return
+	}
+	var payload models.RollbackPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid request payload", nil)
+		return
+	}
+	version, err := s.store.GetShortURLVersion(shortURL, payload.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Version not found", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch version", nil)
+		return
+	}
+
+	state := version.State
+	restored := &models.ShortURL{
+		OriginalURL: state.OriginalURL,
+		Rules:       state.Rules,
+		Targeting:   state.Targeting,
+		Split:       state.Split,
+		Rotation:    state.Rotation,
+	}
+	if !s.checkDestinations(c, linkDestinations(restored)...) {
+		return
+	}
+	// The destinations pass the policy now, so do not bring back a
+	// policy-imposed disable.
+	if state.Disabled && state.DisabledReason == models.DisabledReasonDomainPolicy {
+		state.Disabled, state.DisabledReason = false, ""
+	}
+	updated, ok := s.saveLinkState(c, existing, state, expected, checkVersion, models.LinkChangeRollback, &payload.Version)
+	if !ok {
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Short URL rolled back successfully", updated)
+}
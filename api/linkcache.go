@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"kortlink/internal/cache"
+	"kortlink/internal/models"
+	"time"
+)
+
+const linkCacheTTL = 24 * time.Hour
+
+// cachedLink is the Redis representation of a link. It carries the fields
+// the redirect path needs that are hidden from API responses.
+type cachedLink struct {
+	models.ShortURL
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// cacheLink stores the full link record so redirects served from the cache
+// enforce the same rules as ones served from the database.
+func cacheLink(c *cache.RedisCache, link *models.ShortURL) {
+	entry := cachedLink{ShortURL: *link, PasswordHash: link.PasswordHash}
+	entry.Password = nil
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	_ = c.Set(link.ShortURL, string(data), scheduleTTL(link, linkCacheTTL, time.Now()))
+}
+
+// getCachedLink returns the cached link record, treating entries that cannot
+// be decoded (such as the bare URLs written by older versions) as misses.
+func getCachedLink(c *cache.RedisCache, shortURL string) (*models.ShortURL, bool) {
+	val, err := c.Get(shortURL)
+	if err != nil || val == "" {
+		return nil, false
+	}
+	var entry cachedLink
+	if err := json.Unmarshal([]byte(val), &entry); err != nil || entry.ShortURL.ShortURL == "" {
+		return nil, false
+	}
+	link := entry.ShortURL
+	link.PasswordHash = entry.PasswordHash
+	return &link, true
+}
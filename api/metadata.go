@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"kortlink/internal/cache"
+	"kortlink/internal/metadata"
+	"kortlink/internal/models"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	metadataQueueSize        = 256
+	metadataWorkers          = 4
+	metadataJobTimeout       = 30 * time.Second
+	metadataBackfillInterval = 5 * time.Minute
+)
+
+type metadataJob struct {
+	shortURL    string
+	originalURL string
+}
+
+// metadataQueue fetches destination titles, descriptions and images in the
+// background, so creating or updating a link never waits on the
+// destination site.
+type metadataQueue struct {
+	fetcher *metadata.Fetcher
+	store   Store
+	cache   *cache.RedisCache
+	jobs    chan metadataJob
+
+	// pending holds the jobs in the queue or being fetched, so the backfill
+	// does not queue them again.
+	mu      sync.Mutex
+	pending map[metadataJob]bool
+}
+
+func newMetadataQueue(s Store, c *cache.RedisCache) *metadataQueue {
+	return &metadataQueue{
+		fetcher: metadata.NewFetcher(),
+		store:   s,
+		cache:   c,
+		jobs:    make(chan metadataJob, metadataQueueSize),
+		pending: make(map[metadataJob]bool),
+	}
+}
+
+// Start runs the workers that drain the queue.
+func (q *metadataQueue) Start() {
+	for i := 0; i < metadataWorkers; i++ {
+		go q.work()
+	}
+}
+
+// Enqueue schedules a fetch of originalURL. When the queue is full, as it
+// is during large bulk imports, the job is dropped and left to the backfill.
+func (q *metadataQueue) Enqueue(shortURL, originalURL string) {
+	job := metadataJob{shortURL: shortURL, originalURL: originalURL}
+	if !q.markPending(job) {
+		return
+	}
+	select {
+	case q.jobs <- job:
+	default:
+		q.done(job)
+		log.Debug().Str("short_url", shortURL).Msg("Metadata queue full, leaving fetch to the backfill")
+	}
+}
+
+// StartBackfill queues the links whose destination has not been fetched,
+// once at start and then every interval. Unlike Enqueue it waits for room
+// in the queue, so a large import is worked through a batch at a time.
+func (q *metadataQueue) StartBackfill(interval time.Duration) {
+	go func() {
+		q.backfill()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			q.backfill()
+		}
+	}()
+}
+
+func (q *metadataQueue) backfill() {
+	links, err := q.store.GetShortURLsWithoutMetadata(metadataQueueSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list links without metadata")
+		return
+	}
+	queued := 0
+	for _, link := range links {
+		job := metadataJob{shortURL: link.ShortURL, originalURL: link.OriginalURL}
+		if q.markPending(job) {
+			q.jobs <- job
+			queued++
+		}
+	}
+	if queued > 0 {
+		log.Info().Int("links", queued).Msg("Queued metadata backfill")
+	}
+}
+
+// markPending records job as pending. It returns false if it already is.
+func (q *metadataQueue) markPending(job metadataJob) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending[job] {
+		return false
+	}
+	q.pending[job] = true
+	return true
+}
+
+func (q *metadataQueue) done(job metadataJob) {
+	q.mu.Lock()
+	delete(q.pending, job)
+	q.mu.Unlock()
+}
+
+func (q *metadataQueue) work() {
+	for job := range q.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), metadataJobTimeout)
+		fetched, err := q.fetcher.Fetch(ctx, job.originalURL)
+		cancel()
+
+		// Failed fetches still store empty metadata, which clears what was
+		// read from a previous destination and records the attempt.
+		var meta models.Metadata
+		if err != nil {
+			log.Info().Err(err).Str("short_url", job.shortURL).Msg("Could not fetch destination metadata")
+		} else {
+			meta = models.Metadata{Title: fetched.Title, Description: fetched.Description, ImageURL: fetched.Image}
+		}
+		err = q.store.SetShortURLMetadata(job.shortURL, job.originalURL, meta)
+		q.done(job)
+		if err != nil {
+			log.Error().Err(err).Str("short_url", job.shortURL).Msg("Failed to store destination metadata")
+			continue
+		}
+		_ = q.cache.Delete(job.shortURL)
+	}
+}
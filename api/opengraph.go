@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxOpenGraphTitle       = 300
+	maxOpenGraphDescription = 1000
+)
+
+func validateOpenGraph(og *models.OpenGraph) error {
+	if og == nil {
+		return nil
+	}
+	if utf8.RuneCountInString(og.Title) > maxOpenGraphTitle {
+		return fmt.Errorf("open_graph.title must be at most %d characters", maxOpenGraphTitle)
+	}
+	if utf8.RuneCountInString(og.Description) > maxOpenGraphDescription {
+		return fmt.Errorf("open_graph.description must be at most %d characters", maxOpenGraphDescription)
+	}
+	if og.Image != "" {
+		if err := utility.ValidateUrlRequest(og.Image); err != nil {
+			return fmt.Errorf("open_graph.image: %w", err)
+		}
+	}
+	return nil
+}
+
+func normalizeOpenGraph(og *models.OpenGraph) *models.OpenGraph {
+	if og == nil || *og == (models.OpenGraph{}) {
+		return nil
+	}
+	return og
+}
+
+// renderOpenGraph serves link preview crawlers a page carrying the link's
+// custom Open Graph tags, with a meta refresh to the destination for
+// crawlers that follow it. Tags left empty fall back to the metadata fetched
+// from the destination.
+//
+// Anyone can send a crawler's User-Agent, and these visits are not counted,
+// so the destination is left out for links with a click limit. Links that
+// are locked behind a password also get no fallback to the destination's
+// metadata.
+func renderOpenGraph(c *gin.Context, link *models.ShortURL, locked bool) {
+	og := *link.OpenGraph
+	if !locked {
+		if og.Title == "" {
+			og.Title = link.Title
+		}
+		if og.Description == "" {
+			og.Description = link.Description
+		}
+		if og.Image == "" {
+			og.Image = link.ImageURL
+		}
+	}
+	var destination string
+	if !locked && link.MaxClicks == nil {
+		destination = link.OriginalURL
+	}
+	renderPage(c, http.StatusOK, openGraphPage, gin.H{
+		"OpenGraph":   og,
+		"Destination": destination,
+	})
+}
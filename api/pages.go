@@ -0,0 +1,127 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Small HTML pages served in place of a redirect. They are kept inline so
+// the binary has no template files to ship.
+var threatWarningPage = template.Must(template.New("threat-warning").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Warning: suspicious destination</title>
+<style>
+body { font-family: sans-serif; max-width: 40rem; margin: 4rem auto; padding: 0 1rem; }
+.warning { border-left: 4px solid #c0392b; padding: 0.5rem 1rem; background: #fdecea; }
+code { word-break: break-all; }
+</style>
+</head>
+<body>
+<div class="warning">
+<h1>This link may be unsafe</h1>
+<p>The destination of this short link {{if .Unconfirmed}}may be listed{{else}}has been flagged{{end}} as <strong>{{.ThreatType}}</strong>.</p>
+<p><code>{{.Destination}}</code></p>
+</div>
+<p><a href="{{.ProceedURL}}" rel="nofollow noopener">Continue anyway</a></p>
+</body>
+</html>
+`))
+
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+<style>
+body { font-family: sans-serif; max-width: 24rem; margin: 4rem auto; padding: 0 1rem; }
+.error { color: #c0392b; }
+input, button { font-size: 1rem; padding: 0.4rem; }
+</style>
+</head>
+<body>
+<h1>Password required</h1>
+<p>This link is password protected.</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="password" name="password" autofocus required aria-label="Password">
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{if .Interstitial}}You are leaving{{else}}Link preview{{end}}</title>
+<style>
+body { font-family: sans-serif; max-width: 40rem; margin: 4rem auto; padding: 0 1rem; }
+.destination { border: 1px solid #ddd; border-radius: 4px; padding: 0.5rem 1rem; }
+.destination img { vertical-align: middle; margin-right: 0.4rem; }
+.warning { border-left: 4px solid #c0392b; padding: 0.5rem 1rem; background: #fdecea; }
+.safe { color: #1e8449; }
+.meta { color: #666; }
+code { word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>{{if .Interstitial}}You are about to leave{{else}}Where does this link go?{{end}}</h1>
+{{if .Protected}}
+<p>This link is password protected. Its destination is shown once you unlock it.</p>
+{{else if .Limited}}
+<p>This link can only be followed a limited number of times. Its destination is not shown here.</p>
+{{else}}
+<div class="destination">
+<p>{{if .FaviconURL}}<img src="{{.FaviconURL}}" alt="" width="16" height="16" referrerpolicy="no-referrer">{{end}}{{.Host}}</p>
+{{if .Title}}<p><strong>{{.Title}}</strong></p>{{end}}
+<p><code>{{.Destination}}</code></p>
+</div>
+{{if .Varies}}<p>Some visitors are sent to a different destination.</p>{{end}}
+{{if .ThreatType}}<p class="warning">This destination {{if .Unconfirmed}}may be listed{{else}}has been flagged{{end}} as <strong>{{.ThreatType}}</strong>.</p>
+{{else if .Checked}}<p class="safe">No known threats.</p>
+{{else}}<p>This destination has not been checked against threat lists.</p>{{end}}
+{{end}}
+<p class="meta">Short link created {{.CreatedAt.Format "2 January 2006"}}</p>
+<p><a href="{{.ContinueURL}}" rel="nofollow noopener">Continue</a></p>
+</body>
+</html>
+`))
+
+var openGraphPage = template.Must(template.New("open-graph").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.OpenGraph.Title}}</title>
+<meta property="og:type" content="website">
+{{if .OpenGraph.Title}}<meta property="og:title" content="{{.OpenGraph.Title}}">
+<meta name="twitter:title" content="{{.OpenGraph.Title}}">{{end}}
+{{if .OpenGraph.Description}}<meta property="og:description" content="{{.OpenGraph.Description}}">
+<meta name="description" content="{{.OpenGraph.Description}}">
+<meta name="twitter:description" content="{{.OpenGraph.Description}}">{{end}}
+{{if .OpenGraph.Image}}<meta property="og:image" content="{{.OpenGraph.Image}}">
+<meta name="twitter:image" content="{{.OpenGraph.Image}}">
+<meta name="twitter:card" content="summary_large_image">{{else}}<meta name="twitter:card" content="summary">{{end}}
+{{if .Destination}}<meta http-equiv="refresh" content="0; url={{.Destination}}">{{end}}
+</head>
+<body>
+{{if .Destination}}<p><a href="{{.Destination}}">{{.Destination}}</a></p>{{end}}
+</body>
+</html>
+`))
+
+func renderPage(c *gin.Context, statusCode int, page *template.Template, data interface{}) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(statusCode)
+	if err := page.Execute(c.Writer, data); err != nil {
+		http.Error(c.Writer, "Error rendering page", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// extraPathKey holds the path segments that followed the slug, set by the
+// passthrough fallback route.
+const extraPathKey = "kortlink.extraPath"
+
+var errNoPathPassthrough = errors.New("short URL does not accept extra path segments")
+
+// reservedQueryParams are consumed by the redirect handler itself and never
+// forwarded to destinations.
+var reservedQueryParams = []string{"proceed", qrMarkerParam}
+
+func validatePassthrough(p *models.Passthrough) error {
+	if p == nil {
+		return nil
+	}
+	switch p.QueryConflict {
+	case "", models.QueryConflictDestination, models.QueryConflictRequest, models.QueryConflictAppend:
+		return nil
+	}
+	return fmt.Errorf("query_conflict must be one of destination, request or append, got %q", p.QueryConflict)
+}
+
+// normalizePassthrough returns nil when nothing is passed through, so links
+// without the option store NULL.
+func normalizePassthrough(p *models.Passthrough) *models.Passthrough {
+	if p == nil || (!p.Query && !p.Path) {
+		return nil
+	}
+	return p
+}
+
+// buildDestination applies the link's passthrough options to the selected
+// destination for the current request.
+func buildDestination(destination string, link *models.ShortURL, c *gin.Context) (string, error) {
+	extraPath := c.GetString(extraPathKey)
+	p := link.Passthrough
+	if p == nil {
+		p = &models.Passthrough{}
+	}
+	if extraPath != "" && !p.Path {
+		return "", errNoPathPassthrough
+	}
+
+	incoming := c.Request.URL.Query()
+	for _, key := range reservedQueryParams {
+		incoming.Del(key)
+	}
+	if extraPath == "" && (!p.Query || len(incoming) == 0) {
+		return destination, nil
+	}
+
+	dest, err := url.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+
+	if extraPath != "" {
+		segments := strings.Split(strings.Trim(extraPath, "/"), "/")
+		for _, segment := range segments {
+			if segment == ".." || segment == "." {
+				return "", errNoPathPassthrough
+			}
+		}
+		trailing := strings.HasSuffix(extraPath, "/")
+		dest = dest.JoinPath(segments...)
+		if trailing && !strings.HasSuffix(dest.Path, "/") {
+			dest.Path += "/"
+		}
+	}
+
+	if p.Query && len(incoming) > 0 {
+		query := dest.Query()
+		for key, values := range incoming {
+			_, exists := query[key]
+			switch {
+			case !exists:
+				query[key] = values
+			case p.QueryConflict == models.QueryConflictRequest:
+				query[key] = values
+			case p.QueryConflict == models.QueryConflictAppend:
+				query[key] = append(query[key], values...)
+			}
+		}
+		dest.RawQuery = query.Encode()
+	}
+
+	return dest.String(), nil
+}
+
+// PassthroughFallback serves /<basePath>/<slug>/<extra path> requests, which
+// the router cannot match because a catch-all route would clash with the
+// /:shortURL/... endpoints. Register it as the engine's NoRoute handler.
+func (s *ShortlinkService) PassthroughFallback(basePath string) gin.HandlerFunc {
+	prefix := strings.TrimSuffix(basePath, "/") + "/"
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		method := c.Request.Method
+		if !strings.HasPrefix(path, prefix) || (method != http.MethodGet && method != http.MethodHead && method != http.MethodPost) {
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Not found", nil)
+			return
+		}
+		slug, extra, ok := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+		if !ok || slug == "" || extra == "" {
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Not found", nil)
+			return
+		}
+
+		c.Params = append(c.Params, gin.Param{Key: "shortURL", Value: slug})
+		c.Set(extraPathKey, "/"+extra)
+		if method == http.MethodPost {
+			s.handleUnlock(c)
+			return
+		}
+		s.handleRedirect(c)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	unlockCookieName = "kortlink_unlock"
+	unlockCookieTTL  = 24 * time.Hour
+)
+
+// newCookieSecret returns the configured signing secret, or a random one
+// when none is set. Random secrets do not survive restarts and are not shared
+// between instances, so visitors may have to unlock a link again.
+func newCookieSecret(configured string) []byte {
+	if configured != "" {
+		return []byte(configured)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal().Err(err).Msg("Failed to generate cookie secret")
+	}
+	log.Warn().Msg("LINK_COOKIE_SECRET is not set, using a random secret")
+	return secret
+}
+
+// unlockSignature binds the cookie to the slug, its expiry and the current
+// password hash, so changing the password invalidates issued cookies.
+func (s *ShortlinkService) unlockSignature(link *models.ShortURL, expires int64) string {
+	mac := hmac.New(sha256.New, s.cookieSecret)
+	mac.Write([]byte(link.ShortURL + "\n" + strconv.FormatInt(expires, 10) + "\n" + link.PasswordHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *ShortlinkService) isUnlocked(c *gin.Context, link *models.ShortURL) bool {
+	value, err := c.Cookie(unlockCookieName)
+	if err != nil {
+		return false
+	}
+	expiresPart, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresPart, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.unlockSignature(link, expires)))
+}
+
+// setUnlockCookie scopes the cookie to the link's own path, which also
+// covers passthrough paths below it, whichever of those the visitor unlocked
+// from. The preview path is not below the slug, so it gets its own cookie.
+func (s *ShortlinkService) setUnlockCookie(c *gin.Context, link *models.ShortURL) {
+	expires := time.Now().Add(unlockCookieTTL).Unix()
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	path := strings.TrimSuffix(linkPath(c), previewSuffix)
+	c.SetSameSite(http.SameSiteLaxMode)
+	for _, cookiePath := range []string{path, path + previewSuffix} {
+		c.SetCookie(
+			unlockCookieName,
+			strconv.FormatInt(expires, 10)+"."+s.unlockSignature(link, expires),
+			int(unlockCookieTTL.Seconds()),
+			cookiePath,
+			"",
+			secure,
+			true,
+		)
+	}
+}
+
+// @Summary      Unlock a password-protected short URL
+// @Description  Verifies the password submitted from the unlock form, sets a signed cookie scoped to the short URL and redirects back to it. POSTs to links without a password are redirected like GETs.
+// @Tags         shortlinks
+// @Accept       x-www-form-urlencoded
+// @Produce      html
+// @Param        shortURL   path      string  true  "Short URL"
+// @Param        password   formData  string  true  "Link password"
+// @Success      303        {string}  string  "Redirected back to the short URL"
+// @Failure      401        {string}  string  "Incorrect password"
+// @Failure      404        {string}  string  "Short URL not found"
+// @Router       /api/v1/{shortURL} [post]
+func (s *ShortlinkService) handleUnlock(c *gin.Context) {
+	shortURL, _ := parseSlug(c)
+	link, err := s.store.GetShortURL(shortURL)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
+		return
+	}
+
+	// POSTs to unprotected links are ordinary redirects, so API clients
+	// following a 307/308 link keep their request body.
+	if link.PasswordHash == "" {
+		s.handleRedirect(c)
+		return
+	}
+
+	back := c.Request.URL.Path
+	if c.Request.URL.RawQuery != "" {
+		back += "?" + c.Request.URL.RawQuery
+	}
+
+	if !utility.CheckPassword(link.PasswordHash, c.PostForm("password")) {
+		renderPage(c, http.StatusUnauthorized, passwordPage, gin.H{"Error": "Incorrect password"})
+		return
+	}
+
+	s.setUnlockCookie(c, link)
+	c.Redirect(http.StatusSeeOther, back)
+}
@@ -0,0 +1,429 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	maxPatchBytes         = 1 << 20
+)
+
+// patchFields are the fields of a link a merge patch can change. password is
+// write-only: a string sets it, and "" or null removes protection.
+var patchFields = map[string]bool{
+	"original_url":  true,
+	"utm_source":    true,
+	"utm_medium":    true,
+	"utm_campaign":  true,
+	"utm_term":      true,
+	"utm_content":   true,
+	"password":      true,
+	"redirect_type": true,
+	"passthrough":   true,
+	"rules":         true,
+	"targeting":     true,
+	"split":         true,
+	"rotation":      true,
+	"interstitial":  true,
+	"open_graph":    true,
+	"max_clicks":    true,
+	"activate_at":   true,
+	"deactivate_at": true,
+	"inactive_url":  true,
+	"tags":          true,
+	"folder_id":     true,
+	"notes":         true,
+}
+
+// patchDocument is the document a merge patch applies to: the link's state
+// without the fields a patch cannot change.
+func patchDocument(state models.LinkState) ([]byte, error) {
+	fields, err := stateFields(state)
+	if err != nil {
+		return nil, err
+	}
+	for name := range fields {
+		if !patchFields[name] {
+			delete(fields, name)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// decodeField reads one field of a patched document into state, describing
+// a value of the wrong type in terms of the JSON the client sent.
+func decodeField(state *models.LinkState, name string, value json.RawMessage) error {
+	data, err := json.Marshal(map[string]json.RawMessage{name: value})
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, state)
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	switch {
+	case errors.As(err, &typeErr):
+		expected := jsonTypeName(typeErr.Type)
+		if typeErr.Field != "" && typeErr.Field != name {
+			return fmt.Errorf("%s must be %s, not %s", typeErr.Field, expected, typeErr.Value)
+		}
+		return fmt.Errorf("must be %s, not %s", expected, typeErr.Value)
+	case errors.As(err, &timeErr):
+		return errors.New("must be an RFC 3339 timestamp")
+	}
+	return err
+}
+
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// patchUTM merges the patched utm_* fields into the destination. A patched
+// original_url brings its own UTM parameters, so fields that are not in the
+// patch are taken from it; parameters for fields that are in the patch are
+// replaced. The destination is left as it is when neither is patched.
+func patchUTM(state *models.LinkState, patch map[string]json.RawMessage) error {
+	utm := state.UTM
+	names := map[string]*string{
+		"utm_source":   &utm.UTMSource,
+		"utm_medium":   &utm.UTMMedium,
+		"utm_campaign": &utm.UTMCampaign,
+		"utm_term":     &utm.UTMTerm,
+		"utm_content":  &utm.UTMContent,
+	}
+	_, urlPatched := patch["original_url"]
+	patched := false
+	for name, value := range names {
+		if _, ok := patch[name]; ok {
+			patched = true
+		} else if urlPatched {
+			*value = ""
+		}
+	}
+	if !urlPatched && !patched {
+		return nil
+	}
+
+	destination := state.OriginalURL
+	if patched {
+		u, err := url.Parse(destination)
+		if err != nil {
+			return err
+		}
+		query := u.Query()
+		for name := range names {
+			if _, ok := patch[name]; ok {
+				query.Del(name)
+			}
+		}
+		u.RawQuery = query.Encode()
+		destination = u.String()
+	}
+	destination, utm, err := utility.ApplyUTM(destination, utm)
+	if err != nil {
+		return err
+	}
+	state.OriginalURL = destination
+	state.UTM = utm
+	return nil
+}
+
+// applyLinkPatch applies a merge patch to the state of link and validates
+// the result. Problems with the patch are returned per field; the error is
+// for failures that are not the client's.
+func (s *ShortlinkService) applyLinkPatch(link *models.ShortURL, patch map[string]json.RawMessage) (models.LinkState, map[string]string, error) {
+	current := linkState(link)
+	fieldErrs := make(map[string]string)
+	var password *string
+	passwordValue, passwordPatched := patch["password"]
+	if passwordPatched {
+		if err := json.Unmarshal(passwordValue, &password); err != nil {
+			fieldErrs["password"] = "must be a string, or null to remove the password"
+		}
+	}
+	fields := make(map[string]json.RawMessage, len(patch))
+	for name, value := range patch {
+		switch {
+		case !patchFields[name]:
+			fieldErrs[name] = "unknown field"
+		case name != "password":
+			fields[name] = value
+		}
+	}
+
+	doc, err := patchDocument(current)
+	if err != nil {
+		return current, nil, err
+	}
+	patchData, err := json.Marshal(fields)
+	if err != nil {
+		return current, nil, err
+	}
+	merged, err := jsonpatch.MergePatch(doc, patchData)
+	if err != nil {
+		return current, nil, err
+	}
+	var mergedFields map[string]json.RawMessage
+	if err := json.Unmarshal(merged, &mergedFields); err != nil {
+		return current, nil, err
+	}
+	state := models.LinkState{
+		PasswordHash:   current.PasswordHash,
+		Disabled:       current.Disabled,
+		DisabledReason: current.DisabledReason,
+	}
+	for name, value := range mergedFields {
+		if err := decodeField(&state, name, value); err != nil {
+			fieldErrs[name] = err.Error()
+		}
+	}
+	if len(fieldErrs) > 0 {
+		return state, fieldErrs, nil
+	}
+
+	s.validateLinkState(&state, patch, fieldErrs)
+	if len(fieldErrs) > 0 {
+		return state, fieldErrs, nil
+	}
+	if err := s.screenPatchedDestinations(&state, patch, fieldErrs); err != nil {
+		return state, nil, err
+	}
+	if len(fieldErrs) > 0 {
+		return state, fieldErrs, nil
+	}
+
+	// Hashing is slow, so it waits until the patch is known to be valid.
+	if passwordPatched {
+		state.PasswordHash = ""
+		if password != nil && *password != "" {
+			hash, err := utility.HashPassword(*password)
+			if err != nil {
+				return state, nil, err
+			}
+			state.PasswordHash = hash
+		}
+	}
+	return state, fieldErrs, nil
+}
+
+// validateLinkState checks and normalizes a patched state, recording each
+// problem under the field it concerns.
+func (s *ShortlinkService) validateLinkState(state *models.LinkState, patch map[string]json.RawMessage, fieldErrs map[string]string) {
+	if err := utility.ValidateUrlRequest(state.OriginalURL); err != nil {
+		fieldErrs["original_url"] = err.Error()
+	} else if err := patchUTM(state, patch); err != nil {
+		field := "original_url"
+		if name, _, _ := strings.Cut(err.Error(), " "); strings.HasPrefix(name, "utm_") {
+			field = name
+		}
+		fieldErrs[field] = err.Error()
+	}
+	if state.RedirectType == 0 {
+		state.RedirectType = defaultRedirectType
+	}
+	if err := validateRedirectType(state.RedirectType); err != nil {
+		fieldErrs["redirect_type"] = err.Error()
+	}
+	if err := validatePassthrough(state.Passthrough); err != nil {
+		fieldErrs["passthrough"] = err.Error()
+	}
+	state.Passthrough = normalizePassthrough(state.Passthrough)
+	if err := validateRules(state.Rules); err != nil {
+		fieldErrs["rules"] = err.Error()
+	}
+	if len(state.Rules) == 0 {
+		state.Rules = nil
+	}
+	if err := validateTargeting(state.Targeting); err != nil {
+		fieldErrs["targeting"] = err.Error()
+	}
+	if len(state.Targeting) == 0 {
+		state.Targeting = nil
+	}
+	if err := validateSplit(state.Split); err != nil {
+		fieldErrs["split"] = err.Error()
+	}
+	if state.Split != nil && len(state.Split.Variants) == 0 {
+		state.Split = nil
+	}
+	if err := validateRotation(state.Rotation); err != nil {
+		fieldErrs["rotation"] = err.Error()
+	}
+	state.Rotation = normalizeRotation(state.Rotation)
+	if state.Split != nil && state.Rotation != nil {
+		fieldErrs["rotation"] = "split and rotation cannot be combined"
+	}
+	if err := validateOpenGraph(state.OpenGraph); err != nil {
+		fieldErrs["open_graph"] = err.Error()
+	}
+	state.OpenGraph = normalizeOpenGraph(state.OpenGraph)
+	if state.MaxClicks != nil && *state.MaxClicks < 1 {
+		fieldErrs["max_clicks"] = "max_clicks must be at least 1, or null for no limit"
+	}
+	if err := validateSchedule(state.ActivateAt, state.DeactivateAt, state.InactiveURL); err != nil {
+		field := "deactivate_at"
+		if strings.HasPrefix(err.Error(), "inactive_url") {
+			field = "inactive_url"
+		}
+		fieldErrs[field] = err.Error()
+	}
+	tags, err := normalizeTags(state.Tags)
+	if err != nil {
+		fieldErrs["tags"] = err.Error()
+	}
+	state.Tags = tags
+	if state.FolderID != nil && *state.FolderID == 0 {
+		state.FolderID = nil
+	}
+	if err := checkFolder(s.store, state.FolderID); err != nil {
+		fieldErrs["folder_id"] = err.Error()
+	}
+	if err := validateNotes(&state.Notes); err != nil {
+		fieldErrs["notes"] = err.Error()
+	}
+}
+
+// screenPatchedDestinations runs the domain policy and threat screening
+// over the destinations in the patch. Destinations that are not patched are
+// left alone, so a link disabled by the policy can still be edited; it is
+// re-enabled once all its destinations pass.
+func (s *ShortlinkService) screenPatchedDestinations(state *models.LinkState, patch map[string]json.RawMessage, fieldErrs map[string]string) error {
+	p, err := loadDomainPolicy(s.store)
+	if err != nil {
+		return err
+	}
+	destinations := map[string][]string{"original_url": {state.OriginalURL}}
+	if state.InactiveURL != "" {
+		destinations["inactive_url"] = []string{state.InactiveURL}
+	}
+	for _, rule := range state.Rules {
+		destinations["rules"] = append(destinations["rules"], rule.URL)
+	}
+	for _, rule := range state.Targeting {
+		destinations["targeting"] = append(destinations["targeting"], rule.URL)
+	}
+	if state.Split != nil {
+		for _, variant := range state.Split.Variants {
+			destinations["split"] = append(destinations["split"], variant.URL)
+		}
+	}
+	if state.Rotation != nil {
+		destinations["rotation"] = state.Rotation.URLs
+	}
+
+	passes := true
+	for field, urls := range destinations {
+		if err := s.screenDestinations(p, urls...); err != nil {
+			passes = false
+			if _, ok := patch[field]; ok {
+				fieldErrs[field] = err.Error()
+			}
+		}
+	}
+	if passes && state.Disabled && state.DisabledReason == models.DisabledReasonDomainPolicy {
+		state.Disabled, state.DisabledReason = false, ""
+	}
+	return nil
+}
+
+// @Summary      Partially update a short URL
+// @Description  Applies a JSON Merge Patch (RFC 7396) to a short URL, so only the fields to change are sent: a value sets a field, null clears it and objects such as passthrough or open_graph are merged. Arrays such as tags and rules are replaced as a whole. password sets a new password, and null or "" removes it. All changes are validated together and applied at once; invalid fields are reported individually in data.errors. If-Match must carry the link's ETag, or "*".
+// @Tags         shortlinks
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        shortURL   path      string  true  "Short URL"
+// @Param        If-Match   header    string  true  "ETag of the version the patch is based on"
+// @Param        body       body      object  true  "Merge patch, e.g. {\"deactivate_at\": \"2025-01-01T00:00:00Z\", \"tags\": [\"sale\"]}"
+// @Success      200        {object}  models.ShortURL
+// @Header       200        {string}  ETag    "Version of the updated link"
+// @Failure      400        {object}  models.ValidationErrors  "Invalid fields, in data.errors"
+// @Failure      404        {object}  models.Response
+// @Failure      412        {object}  models.Response
+// @Failure      413        {object}  models.Response
+// @Failure      415        {object}  models.Response
+// @Failure      428        {object}  models.Response
+// @Failure      500        {object}  models.Response
+// @Router       /api/v1/{shortURL} [patch]
+func (s *ShortlinkService) handlePatchShortlink(c *gin.Context) {
+	shortURL := c.Param("shortURL")
+	if mediaType, _, _ := mime.ParseMediaType(c.ContentType()); mediaType != mergePatchContentType && mediaType != "application/json" {
+		utility.WriteJSON(c.Writer, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType, nil)
+		return
+	}
+	expected, checkVersion, ok := ifMatchVersion(c, true)
+	if !ok {
+		return
+	}
+	existing, err := s.store.GetShortURL(shortURL)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
+		return
+	}
+	if checkVersion && existing.Version != expected {
+		preconditionFailed(c, existing.Version)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utility.WriteJSON(c.Writer, http.StatusRequestEntityTooLarge, "Patch is too large", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid request payload", nil)
+		return
+	}
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Patch must be a JSON object", nil)
+		return
+	}
+
+	state, fieldErrs, err := s.applyLinkPatch(existing, patch)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to update short URL", nil)
+		return
+	}
+	if len(fieldErrs) > 0 {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid patch", models.ValidationErrors{Errors: fieldErrs})
+		return
+	}
+
+	updated, ok := s.saveLinkState(c, existing, state, expected, checkVersion, models.LinkChangeUpdate, nil)
+	if !ok {
+		return
+	}
+	utility.WriteJSON(c.Writer, http.StatusOK, "Short URL updated successfully", updated)
+}
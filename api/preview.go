@@ -0,0 +1,106 @@
+package api
+
+import (
+	"kortlink/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// previewSuffix appended to a slug (GET /abc123+) shows the preview page
+// instead of redirecting, like ?preview=1.
+const previewSuffix = "+"
+
+// previewData is rendered by previewPage, both for explicit previews and for
+// links that always show an interstitial.
+type previewData struct {
+	Interstitial bool
+	Protected    bool
+	Limited      bool
+	Destination  string
+	Host         string
+	FaviconURL   string
+	Title        string
+	Varies       bool
+	Checked      bool
+	ThreatType   string
+	Unconfirmed  bool
+	CreatedAt    time.Time
+	ContinueURL  string
+}
+
+// parseSlug strips the preview suffix from the shortURL path parameter.
+func parseSlug(c *gin.Context) (string, bool) {
+	return strings.CutSuffix(c.Param("shortURL"), previewSuffix)
+}
+
+func showsInterstitial(link *models.ShortURL) bool {
+	return link.Interstitial != nil && *link.Interstitial
+}
+
+// destinationVaries reports whether visitors can be sent somewhere other
+// than OriginalURL.
+func destinationVaries(link *models.ShortURL) bool {
+	return len(link.Rules) > 0 || len(link.Targeting) > 0 || link.Split != nil || link.Rotation != nil
+}
+
+// newPreviewData describes destination for the preview page. The favicon is
+// loaded by the visitor's browser from the destination host; the title comes
+// from the fetched metadata.
+func (s *ShortlinkService) newPreviewData(link *models.ShortURL, destination string) previewData {
+	data := previewData{
+		Destination: destination,
+		Varies:      destinationVaries(link),
+		Checked:     s.screener.Enabled(),
+		CreatedAt:   link.CreatedAt,
+	}
+	// The stored title describes OriginalURL, not targeted destinations.
+	if destination == link.OriginalURL {
+		data.Title = link.Title
+	}
+	if u, err := url.Parse(destination); err == nil && u.Host != "" {
+		data.Host = u.Hostname()
+		data.FaviconURL = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/favicon.ico"}).String()
+	}
+	if match, flagged := s.screener.Check(destination); flagged {
+		data.ThreatType = match.ThreatType
+		data.Unconfirmed = !match.Confirmed
+	}
+	return data
+}
+
+// renderPreview shows what a link points to without following it. For
+// password-protected links the destination is only shown once the visitor
+// has unlocked the link, and for links with a click limit not at all, since
+// previews do not use up a click.
+func (s *ShortlinkService) renderPreview(c *gin.Context, link *models.ShortURL) {
+	continueURL := *c.Request.URL
+	continueURL.Path = strings.TrimSuffix(continueURL.Path, previewSuffix)
+	query := continueURL.Query()
+	query.Del("preview")
+	continueURL.RawQuery = query.Encode()
+
+	if link.PasswordHash != "" && !s.isUnlocked(c, link) {
+		renderPage(c, http.StatusOK, previewPage, previewData{
+			Protected:   true,
+			CreatedAt:   link.CreatedAt,
+			ContinueURL: continueURL.String(),
+		})
+		return
+	}
+	if link.MaxClicks != nil {
+		renderPage(c, http.StatusOK, previewPage, previewData{
+			Limited:     true,
+			CreatedAt:   link.CreatedAt,
+			ContinueURL: continueURL.String(),
+		})
+		return
+	}
+
+	data := s.newPreviewData(link, link.OriginalURL)
+	data.ContinueURL = continueURL.String()
+	renderPage(c, http.StatusOK, previewPage, data)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"kortlink/internal/config"
+	"kortlink/internal/models"
+	"kortlink/internal/qr"
+	"kortlink/internal/utility"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// qrMarkerParam is added to the URL encoded in QR codes, so scans can be
+// told apart from other visits in the stats.
+const qrMarkerParam = "qr"
+
+const (
+	defaultQRSize   = 256
+	minQRSize       = 64
+	maxQRSize       = 2048
+	defaultQRMargin = 4
+	maxQRMargin     = 16
+	qrLogoTimeout   = 10 * time.Second
+)
+
+// parseQRColor reads a color as 6 (RRGGBB) or 8 (RRGGBBAA) hex digits, with
+// or without a leading '#'.
+func parseQRColor(value string, fallback color.Color) (color.Color, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(value, "#"))
+	if err != nil || (len(b) != 3 && len(b) != 4) {
+		return nil, fmt.Errorf("invalid color %q, expected RRGGBB or RRGGBBAA", value)
+	}
+	c := color.NRGBA{R: b[0], G: b[1], B: b[2], A: 255}
+	if len(b) == 4 {
+		c.A = b[3]
+	}
+	return c, nil
+}
+
+func parseQRInt(value string, fallback, min, max int, name string) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be a number between %d and %d", name, min, max)
+	}
+	return n, nil
+}
+
+// parseQROptions reads the rendering options from the query string.
+func parseQROptions(c *gin.Context) (qr.Options, error) {
+	var opts qr.Options
+	var err error
+	if opts.Size, err = parseQRInt(c.Query("size"), defaultQRSize, minQRSize, maxQRSize, "size"); err != nil {
+		return opts, err
+	}
+	if opts.Margin, err = parseQRInt(c.Query("margin"), defaultQRMargin, 0, maxQRMargin, "margin"); err != nil {
+		return opts, err
+	}
+	if opts.Foreground, err = parseQRColor(c.Query("fg"), color.Black); err != nil {
+		return opts, err
+	}
+	if opts.Background, err = parseQRColor(c.Query("bg"), color.White); err != nil {
+		return opts, err
+	}
+
+	opts.Level = strings.ToUpper(c.Query("level"))
+	if opts.Level == "" {
+		// A logo hides part of the code, so default to the most redundancy.
+		opts.Level = "M"
+		if c.Query("logo") != "" {
+			opts.Level = "H"
+		}
+	}
+	if _, ok := qr.Levels[opts.Level]; !ok {
+		return opts, fmt.Errorf("level must be one of L, M, Q or H")
+	}
+	return opts, nil
+}
+
+// shortLinkURL is the absolute URL of the short link at path. It is built
+// from PUBLIC_BASE_URL when that is set. Otherwise it comes from the Host
+// header and scheme of this request, which the client controls, and
+// fromRequest is true.
+func shortLinkURL(c *gin.Context, path string) (link string, fromRequest bool) {
+	if config.Envs.PublicBaseURL != "" {
+		return config.Envs.PublicBaseURL + path, false
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + path, true
+}
+
+// @Summary      QR code for a short URL
+// @Description  Renders a QR code that opens the short URL. The encoded URL carries ?qr=1, so scans are reported separately as qr_scans in the link's stats.
+// @Tags         shortlinks
+// @Produce      png
+// @Produce      image/svg+xml
+// @Param        shortURL   path      string  true   "Short URL"
+// @Param        format     query     string  false  "Image format" Enums(png, svg) default(png)
+// @Param        size       query     int     false  "Width and height in pixels, 64-2048" default(256)
+// @Param        level      query     string  false  "Error correction level; defaults to H with a logo, M otherwise" Enums(L, M, Q, H)
+// @Param        margin     query     int     false  "Quiet zone in modules, 0-16" default(4)
+// @Param        fg         query     string  false  "Foreground color as RRGGBB or RRGGBBAA hex" default(000000)
+// @Param        bg         query     string  false  "Background color as RRGGBB or RRGGBBAA hex" default(ffffff)
+// @Param        logo       query     string  false  "URL of a PNG, JPEG or GIF image drawn in the center"
+// @Success      200        {file}    file    "QR code image"
+// @Failure      400        {object}  models.Response
+// @Failure      404        {object}  models.Response
+// @Failure      500        {object}  models.Response
+// @Router       /api/v1/{shortURL}/qr [get]
+func (s *ShortlinkService) handleGetQRCode(c *gin.Context) {
+	shortURL := c.Param("shortURL")
+	if _, err := s.store.GetOriginalURL(shortURL); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
+		return
+	}
+
+	opts, err := parseQROptions(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if logoURL := c.Query("logo"); logoURL != "" {
+		if err := utility.ValidateUrlRequest(logoURL); err != nil {
+			utility.WriteJSON(c.Writer, http.StatusBadRequest, "logo: "+err.Error(), nil)
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), qrLogoTimeout)
+		defer cancel()
+		if opts.Logo, err = s.metadata.fetcher.FetchImage(ctx, logoURL); err != nil {
+			utility.WriteJSON(c.Writer, http.StatusBadRequest, "Could not load logo: "+err.Error(), nil)
+			return
+		}
+	}
+
+	link, fromRequest := shortLinkURL(c, strings.TrimSuffix(c.Request.URL.Path, "/qr"))
+	content := link + "?" + qrMarkerParam + "=1"
+	var (
+		image       []byte
+		contentType string
+	)
+	switch c.DefaultQuery("format", "png") {
+	case "png":
+		image, err = qr.PNG(content, opts)
+		contentType = "image/png"
+	case "svg":
+		image, err = qr.SVG(content, opts)
+		contentType = "image/svg+xml"
+	default:
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "format must be png or svg", nil)
+		return
+	}
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to generate QR code", nil)
+		return
+	}
+
+	// An image built from the request's Host must not be served to
+	// clients that asked for another host.
+	if fromRequest {
+		c.Header("Vary", "Host, X-Forwarded-Proto")
+		c.Header("Cache-Control", "private, max-age=86400")
+	} else {
+		c.Header("Cache-Control", "public, max-age=86400")
+	}
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// clickSource classifies how the visitor reached the link.
+func clickSource(c *gin.Context) string {
+	if c.Query(qrMarkerParam) == "1" {
+		return models.ClickSourceQR
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"fmt"
+	"kortlink/internal/models"
+	"net/http"
+)
+
+const defaultRedirectType = http.StatusFound
+
+func validateRedirectType(redirectType int) error {
+	switch redirectType {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	}
+	return fmt.Errorf("redirect_type must be one of 301, 302, 307 or 308, got %d", redirectType)
+}
+
+func redirectStatus(link *models.ShortURL) int {
+	if link.RedirectType == 0 {
+		return defaultRedirectType
+	}
+	return link.RedirectType
+}
+
+// redirectCacheControl picks the Cache-Control header for a redirect.
+// Permanent redirects may be cached by browsers and proxies for a day, which
+// means repeat visits are not counted. Links whose behaviour depends on
+// server-side state (click limits, schedules, passwords) are never cached,
+// whatever their redirect type, and links that pick a destination per
+// visitor are only cached privately.
+func redirectCacheControl(link *models.ShortURL) string {
+	if link.MaxClicks != nil || link.ActivateAt != nil || link.DeactivateAt != nil || link.PasswordHash != "" {
+		return "no-store"
+	}
+	if len(link.Rules) > 0 || len(link.Targeting) > 0 || link.Split != nil || link.Rotation != nil {
+		return "private, no-cache"
+	}
+	switch redirectStatus(link) {
+	case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		return "public, max-age=86400"
+	default:
+		return "private, no-cache"
+	}
+}
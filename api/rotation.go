@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"kortlink/internal/cache"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"math/rand/v2"
+	"sync"
+)
+
+const maxRotationURLs = 50
+
+func validateRotation(rotation *models.Rotation) error {
+	if rotation == nil || len(rotation.URLs) == 0 {
+		return nil
+	}
+	switch rotation.Mode {
+	case "", models.RotationRoundRobin, models.RotationRandom:
+	default:
+		return fmt.Errorf("rotation.mode must be %q or %q", models.RotationRoundRobin, models.RotationRandom)
+	}
+	if len(rotation.URLs) < 2 || len(rotation.URLs) > maxRotationURLs {
+		return fmt.Errorf("rotation needs between 2 and %d urls", maxRotationURLs)
+	}
+	for i, url := range rotation.URLs {
+		if err := utility.ValidateUrlRequest(url); err != nil {
+			return fmt.Errorf("rotation.urls[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func normalizeRotation(rotation *models.Rotation) *models.Rotation {
+	if rotation == nil || len(rotation.URLs) == 0 {
+		return nil
+	}
+	if rotation.Mode == "" {
+		rotation.Mode = models.RotationRoundRobin
+	}
+	return rotation
+}
+
+func rotationKey(shortURL string) string {
+	return "rotation:" + shortURL
+}
+
+// rotator hands out rotation positions. Round-robin counters live in Redis
+// so every instance advances the same sequence; while Redis is unreachable
+// each instance keeps its own counter, which still spreads its traffic
+// evenly.
+type rotator struct {
+	cache *cache.RedisCache
+
+	mu    sync.Mutex
+	local map[string]uint64
+}
+
+func newRotator(c *cache.RedisCache) *rotator {
+	return &rotator{cache: c, local: make(map[string]uint64)}
+}
+
+func (r *rotator) next(shortURL string, rotation *models.Rotation) string {
+	n := uint64(len(rotation.URLs))
+	if rotation.Mode == models.RotationRandom {
+		return rotation.URLs[rand.Uint64N(n)]
+	}
+	if count, err := r.cache.Incr(rotationKey(shortURL)); err == nil {
+		return rotation.URLs[uint64(count-1)%n]
+	}
+
+	r.mu.Lock()
+	count := r.local[shortURL]
+	r.local[shortURL] = count + 1
+	r.mu.Unlock()
+	return rotation.URLs[count%n]
+}
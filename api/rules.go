@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"kortlink/internal/models"
+	"kortlink/internal/rules"
+	"kortlink/internal/utility"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const maxRedirectRules = 20
+
+// validateRules compiles every condition, so rules that would fail at
+// redirect time are rejected when the link is saved.
+func validateRules(redirectRules []models.RedirectRule) error {
+	if len(redirectRules) > maxRedirectRules {
+		return fmt.Errorf("at most %d rules are allowed", maxRedirectRules)
+	}
+	for i, rule := range redirectRules {
+		if _, err := rules.Compile(rule.Condition); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		if err := utility.ValidateUrlRequest(rule.URL); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ruleRequest collects the request attributes conditions can test.
+func ruleRequest(c *gin.Context, v visitor) rules.Request {
+	headers := make(map[string]string, len(c.Request.Header))
+	for name, values := range c.Request.Header {
+		headers[strings.ToLower(name)] = values[0]
+	}
+	query := make(map[string]string)
+	for name, values := range c.Request.URL.Query() {
+		query[name] = values[0]
+	}
+	return rules.Request{
+		Time:      time.Now(),
+		Headers:   headers,
+		Query:     query,
+		IP:        c.ClientIP(),
+		Country:   v.Country,
+		Continent: v.Continent,
+		OS:        v.OS,
+		Device:    v.Device,
+		Browser:   v.Browser,
+	}
+}
+
+// matchRules returns the URL of the first rule whose condition holds. A
+// condition that fails to evaluate counts as not matching.
+func (s *ShortlinkService) matchRules(c *gin.Context, link *models.ShortURL, v visitor) (string, bool) {
+	req := ruleRequest(c, v)
+	for i, rule := range link.Rules {
+		matched, err := s.rules.Match(rule.Condition, req)
+		if err != nil {
+			log.Debug().Err(err).Str("short_url", link.ShortURL).Int("rule", i).Msg("Rule evaluation failed")
+			continue
+		}
+		if matched {
+			return rule.URL, true
+		}
+	}
+	return "", false
+}
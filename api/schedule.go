@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func validateSchedule(activateAt, deactivateAt *time.Time, inactiveURL string) error {
+	if activateAt != nil && deactivateAt != nil && !deactivateAt.After(*activateAt) {
+		return errors.New("deactivate_at must be after activate_at")
+	}
+	if inactiveURL != "" {
+		if err := utility.ValidateUrlRequest(inactiveURL); err != nil {
+			return errors.New("inactive_url: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// checkSchedule responds for links outside their activation window and
+// reports whether it did. It runs on every redirect, including ones served
+// from the cache, so a pre-warmed entry never redirects early or late.
+func checkSchedule(c *gin.Context, link *models.ShortURL, now time.Time) bool {
+	status, message := 0, ""
+	switch {
+	case link.ActivateAt != nil && now.Before(*link.ActivateAt):
+		status, message = http.StatusNotFound, "Short URL is not active yet"
+	case link.DeactivateAt != nil && !now.Before(*link.DeactivateAt):
+		status, message = http.StatusGone, "Short URL is no longer active"
+	default:
+		return false
+	}
+
+	c.Header("Cache-Control", "no-store")
+	if link.InactiveURL != "" {
+		c.Redirect(http.StatusFound, link.InactiveURL)
+		return true
+	}
+	utility.WriteJSON(c.Writer, status, message, nil)
+	return true
+}
+
+// scheduleTTL shortens a cache TTL so the entry expires at the link's next
+// activation boundary.
+func scheduleTTL(link *models.ShortURL, ttl time.Duration, now time.Time) time.Duration {
+	for _, boundary := range []*time.Time{link.ActivateAt, link.DeactivateAt} {
+		if boundary == nil || !boundary.After(now) {
+			continue
+		}
+		if until := boundary.Sub(now); until < ttl {
+			ttl = until
+		}
+	}
+	return ttl
+}
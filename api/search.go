@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxNotesLength     = 2000
+	maxSearchQuery     = 200
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+func validateNotes(notes *string) error {
+	if notes != nil && utf8.RuneCountInString(*notes) > maxNotesLength {
+		return fmt.Errorf("notes must be at most %d characters", maxNotesLength)
+	}
+	return nil
+}
+
+// notesText is the notes column value for a link.
+func notesText(link *models.ShortURL) string {
+	if link.Notes == nil {
+		return ""
+	}
+	return *link.Notes
+}
+
+// searchTerms splits a websearch-style query into the terms to highlight,
+// dropping quotes, the OR operator and excluded (-term) words.
+func searchTerms(query string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ReplaceAll(query, `"`, " ")) {
+		if strings.HasPrefix(word, "-") || strings.EqualFold(word, "or") {
+			continue
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// termsPattern matches any of the terms, case-insensitively, preferring
+// the longest when they overlap.
+func termsPattern(terms []string) *regexp.Regexp {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// highlight HTML-escapes text and wraps every match of pattern in <mark>
+// tags. ok is false when nothing matches.
+func highlight(text string, pattern *regexp.Regexp) (string, bool) {
+	matches := pattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		b.WriteString("<mark>" + html.EscapeString(text[m[0]:m[1]]) + "</mark>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String(), true
+}
+
+// searchHighlights highlights the query terms in the searchable fields of
+// a result.
+func searchHighlights(link *models.ShortURL, query string) map[string]string {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	pattern := termsPattern(terms)
+	fields := map[string]string{
+		"original_url": link.OriginalURL,
+		"short_url":    link.ShortURL,
+		"title":        link.Title,
+		"notes":        notesText(link),
+		"tags":         strings.Join(link.Tags, ", "),
+	}
+	highlights := make(map[string]string)
+	for name, text := range fields {
+		if marked, ok := highlight(text, pattern); ok {
+			highlights[name] = marked
+		}
+	}
+	if len(highlights) == 0 {
+		return nil
+	}
+	return highlights
+}
+
+func parseSearchPage(c *gin.Context) (limit, offset int, err error) {
+	limit, offset = defaultSearchLimit, 0
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxSearchLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must not be negative")
+		}
+	}
+	return limit, offset, nil
+}
+
+// @Summary      Search short URLs
+// @Description  Full-text search over the destination URL, slug, title, notes and tags. Words match anywhere in these fields, and so do fragments such as part of a path; quoted phrases, OR and -word are supported. Results are ranked with title and slug matches first, and each carries its matching fields with the terms wrapped in <mark> tags (the text is HTML-escaped).
+// @Tags         shortlinks
+// @Produce      json
+// @Param        q          query     string  true   "Search query"
+// @Param        tag        query     string  false  "Only links with this tag"
+// @Param        folder_id  query     int     false  "Only links in this folder, 0 for links in no folder"
+// @Param        limit      query     int     false  "Results per page, 1-100" default(20)
+// @Param        offset     query     int     false  "Results to skip" default(0)
+// @Success      200        {object}  models.SearchResponse
+// @Failure      400        {object}  models.Response
+// @Failure      500        {object}  models.Response
+// @Router       /api/v1/shortlinks/search [get]
+func (s *ShortlinkService) handleSearchShortlinks(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "q is required", nil)
+		return
+	}
+	if utf8.RuneCountInString(query) > maxSearchQuery {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, fmt.Sprintf("q must be at most %d characters", maxSearchQuery), nil)
+		return
+	}
+	filter, err := parseLinkFilter(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	limit, offset, err := parseSearchPage(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	results, err := s.store.SearchShortURLs(query, filter, limit, offset)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to search short URLs", nil)
+		return
+	}
+	for i := range results {
+		results[i].Highlights = searchHighlights(&results[i].ShortURL, query)
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Search completed successfully", models.SearchResponse{
+		Query:   query,
+		Limit:   limit,
+		Offset:  offset,
+		Results: results,
+	})
+}
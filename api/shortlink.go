@@ -1,32 +1,65 @@
 package api
 
 import (
+	"errors"
 	"kortlink/internal/models"
 	"kortlink/internal/utility"
 	"net/http"
 	"time"
 
 	"kortlink/internal/cache"
+	"kortlink/internal/geoip"
+	"kortlink/internal/policy"
+	"kortlink/internal/rules"
+	"kortlink/internal/threat"
+	"kortlink/internal/useragent"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
 type ShortlinkService struct {
-	store Store
-	cache *cache.RedisCache
+	store        Store
+	cache        *cache.RedisCache
+	screener     *threat.Screener
+	geo          *geoip.Resolver
+	rules        *rules.Engine
+	rotator      *rotator
+	metadata     *metadataQueue
+	cookieSecret []byte
 }
 
-func NewShortlinkService(s Store, c *cache.RedisCache) *ShortlinkService {
-	return &ShortlinkService{store: s, cache: c}
+func NewShortlinkService(s Store, c *cache.RedisCache, screener *threat.Screener, geo *geoip.Resolver, metadata *metadataQueue, cookieSecret []byte) *ShortlinkService {
+	return &ShortlinkService{
+		store:        s,
+		cache:        c,
+		screener:     screener,
+		geo:          geo,
+		rules:        rules.NewEngine(),
+		rotator:      newRotator(c),
+		metadata:     metadata,
+		cookieSecret: cookieSecret,
+	}
 }
 
 func (s *ShortlinkService) ShortlinkRoutes(r *gin.RouterGroup) {
 	r.POST("/shortlink", s.handleCreateShortlink)
 	r.GET("/:shortURL", s.handleRedirect)
+	r.POST("/:shortURL", s.handleUnlock)
 	r.PUT("/:shortURL", s.handleUpdateShortlink)
+	r.PATCH("/:shortURL", s.handlePatchShortlink)
 	r.DELETE("/:shortURL", s.handleDeleteShortlink)
 	r.GET("/:shortURL/stats", s.handleGetStats)
+	r.GET("/:shortURL/qr", s.handleGetQRCode)
 	r.GET("/shortlinks", s.handleGetAllShortlinks)
+	r.POST("/shortlinks/bulk", s.handleBulkCreateShortlinks)
+	r.POST("/shortlinks/bulk/actions", s.handleBulkAction)
+	r.GET("/shortlinks/campaigns", s.handleGetCampaignStats)
+	r.GET("/shortlinks/search", s.handleSearchShortlinks)
+	r.GET("/shortlinks/trash", s.handleGetTrash)
+	r.POST("/:shortURL/restore", s.handleRestoreShortlink)
+	r.GET("/:shortURL/history", s.handleGetHistory)
+	r.POST("/:shortURL/rollback", s.handleRollbackShortlink)
 	r.GET("/debug/healthCheck", s.handleHealthCheck)
 }
 
@@ -51,82 +84,285 @@ func (s *ShortlinkService) handleCreateShortlink(c *gin.Context) {
 		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid request payload", nil)
 		return
 	}
-	if err := utility.ValidateUrlRequest(payload.OriginalURL); err != nil {
+	p, err := loadDomainPolicy(s.store)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to check domain policy", nil)
+		return
+	}
+	folderExists := func(folderID *int) error { return checkFolder(s.store, folderID) }
+	if err := s.validateNewLink(&payload, p, folderExists); err != nil {
 		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
 
-	shortURL := utility.GenerateShortURL()
-	now := time.Now()
-	shortLink := &models.ShortURL{
-		OriginalURL: payload.OriginalURL,
-		ShortURL:    shortURL,
-		AccessCount: 0,
-		CreatedAt:   now,
+	shortLink, err := newShortLink(&payload, time.Now())
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to create short link", nil)
+		return
+	}
+	// Slugs of links in the trash are not reissued, so pick another one
+	// when the generated slug is taken.
+	for attempt := 1; ; attempt++ {
+		err = s.store.CreateShortURL(shortLink)
+		if !errors.Is(err, ErrShortURLTaken) || attempt == maxSlugAttempts {
+			break
+		}
+		shortLink.ShortURL = utility.GenerateShortURL()
 	}
-
-	err := s.store.CreateShortURL(shortLink)
 	if err != nil {
 		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to create short link", nil)
 		return
 	}
-	_ = s.cache.Set(shortLink.ShortURL, shortLink.OriginalURL, 24*time.Hour)
+	recordHistory(s.store, c, models.LinkChangeCreate, nil, linkChange{after: shortLink})
+	cacheLink(s.cache, shortLink)
+	s.metadata.Enqueue(shortLink.ShortURL, shortLink.OriginalURL)
+	setLinkETag(c, shortLink)
 	utility.WriteJSON(c.Writer, http.StatusCreated, "Short link created successfully", shortLink)
 }
 
+// validateNewLink checks a create payload and normalizes it in place, using
+// folderExists to check its folder. The error describes the first problem
+// found and is safe to return to the client.
+func (s *ShortlinkService) validateNewLink(payload *models.ShortURL, p *policy.DomainPolicy, folderExists func(folderID *int) error) error {
+	if err := utility.ValidateUrlRequest(payload.OriginalURL); err != nil {
+		return err
+	}
+	originalURL, utm, err := utility.ApplyUTM(payload.OriginalURL, payload.UTM)
+	if err != nil {
+		return err
+	}
+	payload.OriginalURL = originalURL
+	payload.UTM = utm
+
+	if payload.MaxClicks != nil && *payload.MaxClicks < 1 {
+		return errors.New("max_clicks must be at least 1")
+	}
+	if err := validateSchedule(payload.ActivateAt, payload.DeactivateAt, payload.InactiveURL); err != nil {
+		return err
+	}
+	if err := validateRedirectType(payload.RedirectType); err != nil {
+		return err
+	}
+	if err := validatePassthrough(payload.Passthrough); err != nil {
+		return err
+	}
+	if err := validateRules(payload.Rules); err != nil {
+		return err
+	}
+	if len(payload.Rules) == 0 {
+		payload.Rules = nil
+	}
+	if err := validateTargeting(payload.Targeting); err != nil {
+		return err
+	}
+	if len(payload.Targeting) == 0 {
+		payload.Targeting = nil
+	}
+	if err := validateSplit(payload.Split); err != nil {
+		return err
+	}
+	if payload.Split != nil && len(payload.Split.Variants) == 0 {
+		payload.Split = nil
+	}
+	if err := validateRotation(payload.Rotation); err != nil {
+		return err
+	}
+	payload.Rotation = normalizeRotation(payload.Rotation)
+	if err := validateOpenGraph(payload.OpenGraph); err != nil {
+		return err
+	}
+	payload.OpenGraph = normalizeOpenGraph(payload.OpenGraph)
+	if payload.Split != nil && payload.Rotation != nil {
+		return errors.New("split and rotation cannot be combined")
+	}
+	if payload.RedirectType == 0 {
+		payload.RedirectType = defaultRedirectType
+	}
+	if payload.Tags, err = normalizeTags(payload.Tags); err != nil {
+		return err
+	}
+	if err := folderExists(payload.FolderID); err != nil {
+		return err
+	}
+	if err := validateNotes(payload.Notes); err != nil {
+		return err
+	}
+	return s.screenDestinations(p, linkDestinations(payload)...)
+}
+
+// newShortLink builds a link with a fresh slug from a validated payload.
+func newShortLink(payload *models.ShortURL, now time.Time) (*models.ShortURL, error) {
+	shortLink := &models.ShortURL{
+		OriginalURL:  payload.OriginalURL,
+		ShortURL:     utility.GenerateShortURL(),
+		AccessCount:  0,
+		MaxClicks:    payload.MaxClicks,
+		ActivateAt:   payload.ActivateAt,
+		DeactivateAt: payload.DeactivateAt,
+		InactiveURL:  payload.InactiveURL,
+		RedirectType: payload.RedirectType,
+		Passthrough:  normalizePassthrough(payload.Passthrough),
+		UTM:          payload.UTM,
+		Rules:        payload.Rules,
+		Targeting:    payload.Targeting,
+		Split:        payload.Split,
+		Rotation:     payload.Rotation,
+		Interstitial: payload.Interstitial,
+		OpenGraph:    payload.OpenGraph,
+		Tags:         payload.Tags,
+		Notes:        payload.Notes,
+		CreatedAt:    now,
+	}
+	if payload.FolderID != nil && *payload.FolderID != 0 {
+		shortLink.FolderID = payload.FolderID
+	}
+	if payload.Password != nil && *payload.Password != "" {
+		hash, err := utility.HashPassword(*payload.Password)
+		if err != nil {
+			return nil, err
+		}
+		shortLink.PasswordHash = hash
+		shortLink.PasswordProtected = true
+	}
+	return shortLink, nil
+}
+
 // @Summary      Redirect to the original URL
 // @Description  Redirects to the original URL based on the provided short URL
 // @Tags         shortlinks
 // @Param        shortURL   path      string  true  "Short URL"
-// @Success      302        {string}  string  "Redirected to the original URL"
+// @Param        proceed    query     string  false "Set to 1 to skip the threat warning and interstitial pages"
+// @Param        preview    query     string  false "Set to 1 (or append + to the short URL) to show the preview page instead of redirecting"
+// @Success      200        {string}  string  "Preview page, interstitial page, Open Graph page for link preview crawlers or warning page for a flagged destination"
+// @Success      302        {string}  string  "Redirected to the original URL (301, 307 or 308 when the link's redirect_type says so)"
+// @Header       302        {string}  ETag    "Version of the link, for If-Match"
 // @Failure      400        {string}  string  "Short URL is required"
+// @Failure      401        {string}  string  "Password form for a protected link"
 // @Failure      404        {string}  string  "Short URL not found"
+// @Failure      404        {string}  string  "Short URL is not active yet"
+// @Failure      410        {string}  string  "Short URL is disabled, expired or has reached its click limit"
 // @Failure      500        {string}  string  "Failed to update access count"
 // @Router       /api/v1/{shortURL} [get]
 func (s *ShortlinkService) handleRedirect(c *gin.Context) {
-	shortURL := c.Param("shortURL")
+	shortURL, preview := parseSlug(c)
+	preview = preview || c.Query("preview") == "1"
 	if shortURL == "" {
 		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Short URL is required", nil)
 		return
 	}
 
-	originalURL, err := s.cache.Get(shortURL)
-	if err == nil && originalURL != "" {
-		err = s.store.IncrementAccessCount(shortURL)
+	link, ok := getCachedLink(s.cache, shortURL)
+	if !ok {
+		var err error
+		link, err = s.store.GetShortURL(shortURL)
 		if err != nil {
-			utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to update access count", nil)
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
 			return
 		}
-		c.Redirect(http.StatusFound, originalURL)
+		cacheLink(s.cache, link)
+	}
+	setLinkETag(c, link)
+	if link.Disabled {
+		utility.WriteJSON(c.Writer, http.StatusGone, "Short URL is disabled", nil)
+		return
+	}
+	if checkSchedule(c, link, time.Now()) {
 		return
 	}
+	// The cached access count can only be behind, so this never refuses a
+	// visit that followLink would count.
+	if link.MaxClicks != nil && link.AccessCount >= *link.MaxClicks {
+		utility.WriteJSON(c.Writer, http.StatusGone, "Short URL has reached its click limit", nil)
+		return
+	}
+	if preview {
+		s.renderPreview(c, link)
+		return
+	}
+	locked := link.PasswordHash != "" && !s.isUnlocked(c, link)
+	if link.OpenGraph != nil {
+		c.Header("Vary", "User-Agent")
+		if useragent.IsPreviewCrawler(c.Request.UserAgent()) {
+			renderOpenGraph(c, link, locked)
+			return
+		}
+	}
+	if locked {
+		renderPage(c, http.StatusUnauthorized, passwordPage, nil)
+		return
+	}
+
+	s.followLink(c, link)
+}
 
-	url, err := s.store.GetOriginalURL(shortURL)
+// followLink counts the visit and redirects to the link's destination.
+// Destinations flagged by the threat lists after the link was created get a
+// warning page first; the visitor continues with ?proceed=1. Links with an
+// interstitial count the visit and show the destination instead of
+// redirecting.
+func (s *ShortlinkService) followLink(c *gin.Context, link *models.ShortURL) {
+	shortURL := link.ShortURL
+	v := s.identifyVisitor(c)
+	url, variant := s.selectDestination(c, link, v)
+	destination, err := buildDestination(url, link, c)
 	if err != nil {
 		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
 		return
 	}
+	if c.Query("proceed") != "1" {
+		if match, flagged := s.screener.Check(destination); flagged {
+			proceed := *c.Request.URL
+			query := proceed.Query()
+			query.Set("proceed", "1")
+			proceed.RawQuery = query.Encode()
+			renderPage(c, http.StatusOK, threatWarningPage, gin.H{
+				"ThreatType":  match.ThreatType,
+				"Unconfirmed": !match.Confirmed,
+				"Destination": destination,
+				"ProceedURL":  proceed.String(),
+			})
+			return
+		}
+	}
 
-	_ = s.cache.Set(shortURL, originalURL, 24*time.Hour)
-	err = s.store.IncrementAccessCount(shortURL)
-	if err != nil {
+	if err := s.store.IncrementAccessCount(shortURL); err != nil {
+		if errors.Is(err, ErrClickLimitReached) {
+			utility.WriteJSON(c.Writer, http.StatusGone, "Short URL has reached its click limit", nil)
+			return
+		}
 		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to update access count", nil)
 		return
 	}
-
-	c.Redirect(http.StatusFound, url)
+	// The visit is already counted, so a failure here only loses analytics.
+	if err := s.store.RecordClick(shortURL, models.Click{Country: v.Country, Variant: variant, Source: clickSource(c)}); err != nil {
+		log.Error().Err(err).Str("short_url", shortURL).Msg("Failed to record click")
+	}
+	if showsInterstitial(link) && c.Query("proceed") != "1" {
+		data := s.newPreviewData(link, destination)
+		data.Interstitial = true
+		data.Varies = false
+		data.ContinueURL = destination
+		renderPage(c, http.StatusOK, previewPage, data)
+		return
+	}
+	c.Header("Cache-Control", redirectCacheControl(link))
+	c.Redirect(redirectStatus(link), destination)
 }
 
 // @Summary      Update a short URL
-// @Description  Update the original URL for a given short URL
+// @Description  Update the original URL for a given short URL. If-Match must carry the ETag the link was read with (or "*" to overwrite whatever is there); if the link has changed since, nothing is updated and 412 is returned with the current ETag.
 // @Tags         shortlinks
 // @Accept       json
 // @Produce      json
 // @Param        shortURL   path      string      true  "Short URL"
+// @Param        If-Match   header    string      true  "ETag of the version the update is based on"
 // @Param        body       body      models.ShortURL  true  "New original URL"
 // @Success      200        {string}  string      "Short URL updated successfully"
+// @Header       200        {string}  ETag        "Version of the updated link"
 // @Failure      400        {string}  string      "Invalid request payload or Short URL is required"
 // @Failure      404        {string}  string      "Short URL not found"
+// @Failure      412        {string}  string      "Short URL has been changed since the version in If-Match"
+// @Failure      428        {string}  string      "If-Match header is required"
 // @Failure      500        {string}  string      "Failed to update short URL"
 // @Router       /api/v1/{shortURL} [put]
 func (s *ShortlinkService) handleUpdateShortlink(c *gin.Context) {
@@ -135,11 +371,19 @@ func (s *ShortlinkService) handleUpdateShortlink(c *gin.Context) {
 		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Short URL is required", nil)
 		return
 	}
-	_, err := s.store.GetOriginalURL(shortURL)
+	expected, checkVersion, ok := ifMatchVersion(c, true)
+	if !ok {
+		return
+	}
+	existing, err := s.store.GetShortURL(shortURL)
 	if err != nil {
 		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
 		return
 	}
+	if checkVersion && existing.Version != expected {
+		preconditionFailed(c, existing.Version)
+		return
+	}
 
 	var payload models.ShortURL
 	if err := c.ShouldBindJSON(&payload); err != nil {
@@ -151,22 +395,166 @@ func (s *ShortlinkService) handleUpdateShortlink(c *gin.Context) {
 		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
+	originalURL, utm, err := utility.ApplyUTM(payload.OriginalURL, payload.UTM)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	payload.OriginalURL = originalURL
+	if payload.MaxClicks != nil && *payload.MaxClicks < 0 {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "max_clicks must not be negative", nil)
+		return
+	}
+	// The schedule is replaced as a whole, so omitted fields are cleared.
+	if err := validateSchedule(payload.ActivateAt, payload.DeactivateAt, payload.InactiveURL); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := validateRedirectType(payload.RedirectType); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := validatePassthrough(payload.Passthrough); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := validateRules(payload.Rules); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// Omitted rules keep the current ones; an empty list removes them.
+	if payload.Rules == nil {
+		payload.Rules = existing.Rules
+	} else if len(payload.Rules) == 0 {
+		payload.Rules = nil
+	}
+	if err := validateTargeting(payload.Targeting); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// Same for targeting keeps the current rules; an empty list removes them.
+	if payload.Targeting == nil {
+		payload.Targeting = existing.Targeting
+	} else if len(payload.Targeting) == 0 {
+		payload.Targeting = nil
+	}
+	if err := validateSplit(payload.Split); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// Likewise for the split: a split without variants removes it.
+	if payload.Split == nil {
+		payload.Split = existing.Split
+	} else if len(payload.Split.Variants) == 0 {
+		payload.Split = nil
+	}
+	if err := validateRotation(payload.Rotation); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// And for the rotation: a rotation without urls removes it.
+	if payload.Rotation == nil {
+		payload.Rotation = existing.Rotation
+	} else {
+		payload.Rotation = normalizeRotation(payload.Rotation)
+	}
+	if payload.Split != nil && payload.Rotation != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "split and rotation cannot be combined", nil)
+		return
+	}
+	if err := validateOpenGraph(payload.OpenGraph); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if payload.Tags, err = normalizeTags(payload.Tags); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := checkFolder(s.store, payload.FolderID); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := validateNotes(payload.Notes); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if !s.checkDestinations(c, linkDestinations(&payload)...) {
+		return
+	}
 
-	if err := s.store.UpdateShortURL(shortURL, payload.OriginalURL); err != nil {
-		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to update short URL", nil)
+	// The new configuration is written in one transaction, together with
+	// the version check and bump, so an update is applied whole or not at
+	// all.
+	state := linkState(existing)
+	state.OriginalURL = payload.OriginalURL
+	state.UTM = utm
+	// The new destination passed the policy, so lift a policy-imposed disable.
+	if state.Disabled && state.DisabledReason == models.DisabledReasonDomainPolicy {
+		state.Disabled, state.DisabledReason = false, ""
+	}
+	if payload.Password != nil {
+		state.PasswordHash = ""
+		if *payload.Password != "" {
+			if state.PasswordHash, err = utility.HashPassword(*payload.Password); err != nil {
+				utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to update short URL", nil)
+				return
+			}
+		}
+	}
+	if payload.MaxClicks != nil {
+		state.MaxClicks = payload.MaxClicks
+		if *payload.MaxClicks == 0 {
+			state.MaxClicks = nil
+		}
+	}
+	if payload.RedirectType != 0 {
+		state.RedirectType = payload.RedirectType
+	}
+	if payload.Passthrough != nil {
+		state.Passthrough = normalizePassthrough(payload.Passthrough)
+	}
+	state.Rules = payload.Rules
+	state.Targeting = payload.Targeting
+	state.Split = payload.Split
+	state.Rotation = payload.Rotation
+	// An open_graph object with no fields removes the custom tags.
+	if payload.OpenGraph != nil {
+		state.OpenGraph = normalizeOpenGraph(payload.OpenGraph)
+	}
+	if payload.Interstitial != nil {
+		state.Interstitial = *payload.Interstitial
+	}
+	state.ActivateAt = payload.ActivateAt
+	state.DeactivateAt = payload.DeactivateAt
+	state.InactiveURL = payload.InactiveURL
+	if payload.Tags != nil {
+		state.Tags = payload.Tags
+	}
+	if payload.Notes != nil {
+		state.Notes = *payload.Notes
+	}
+	if payload.FolderID != nil {
+		state.FolderID = payload.FolderID
+		if *payload.FolderID == 0 {
+			state.FolderID = nil
+		}
+	}
+	if _, ok := s.saveLinkState(c, existing, state, expected, checkVersion, models.LinkChangeUpdate, nil); !ok {
 		return
 	}
-	_ = s.cache.Set(shortURL, payload.OriginalURL, 24*time.Hour)
 	utility.WriteJSON(c.Writer, http.StatusOK, "Short URL updated successfully", nil)
 }
 
 // @Summary      Get short URL statistics
-// @Description  Fetches the statistics (e.g., access count) for a given short URL
+// @Description  Fetches the statistics (e.g., access count, QR code scans, clicks per country and per split variant) for a given short URL
 // @Tags         shortlinks
 // @Param        shortURL   path      string  true  "Short URL"
-// @Success      200        {object}  map[string]interface{}  "Statistics fetched successfully"
+// @Success      200        {object}  models.ShortURLStats  "Statistics fetched successfully"
+// @Header       200        {string}  ETag    "Version of the link, for If-Match"
 // @Failure      400        {string}  string  "Short URL is required"
 // @Failure      404        {string}  string  "Short URL not found"
+// @Failure      500        {string}  string  "Failed to fetch statistics"
 // @Router       /api/v1/{shortURL}/stats [get]
 func (s *ShortlinkService) handleGetStats(c *gin.Context) {
 	shortURL := c.Param("shortURL")
@@ -175,22 +563,47 @@ func (s *ShortlinkService) handleGetStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := s.store.GetShortURLStats(shortURL)
+	link, err := s.store.GetShortURLStats(shortURL)
 	if err != nil {
 		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
 		return
 	}
+	countries, err := s.store.GetClickCountries(shortURL)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch statistics", nil)
+		return
+	}
+	variants, err := s.store.GetClickVariants(shortURL)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch statistics", nil)
+		return
+	}
+	qrScans, err := s.store.CountClicksBySource(shortURL, models.ClickSourceQR)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch statistics", nil)
+		return
+	}
+	setLinkETag(c, link)
+	stats := models.ShortURLStats{
+		ShortURL:  *link,
+		QRScans:   qrScans,
+		Countries: countries,
+		Variants:  variantStats(link.Split, variants),
+	}
 
 	utility.WriteJSON(c.Writer, http.StatusOK, "Statistics fetched successfully", stats)
 }
 
 // @Summary      Delete a short URL
-// @Description  Deletes a given short URL and its related data
+// @Description  Moves a short URL to the trash. It stops redirecting at once and can be restored until it is purged, after TRASH_RETENTION (30 days by default); its slug is not reissued in the meantime.
 // @Tags         shortlinks
 // @Param        shortURL   path      string  true  "Short URL"
+// @Param        If-Match   header    string  true  "ETag of the version the delete is based on, or *"
 // @Success      200        {string}  string  "Short URL deleted successfully"
 // @Failure      400        {string}  string  "Short URL is required"
 // @Failure      404        {string}  string  "Short URL not found"
+// @Failure      412        {string}  string  "Short URL has been changed since the version in If-Match"
+// @Failure      428        {string}  string  "If-Match header is required"
 // @Failure      500        {string}  string  "Failed to delete short URL"
 // @Router      /api/v1/{shortURL} [delete]
 func (s *ShortlinkService) handleDeleteShortlink(c *gin.Context) {
@@ -199,28 +612,48 @@ func (s *ShortlinkService) handleDeleteShortlink(c *gin.Context) {
 		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Short URL is required", nil)
 		return
 	}
-	_, err := s.store.GetOriginalURL(shortURL)
+	expected, checkVersion, ok := ifMatchVersion(c, true)
+	if !ok {
+		return
+	}
+	link, err := s.store.GetShortURL(shortURL)
 	if err != nil {
 		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found", nil)
 		return
 	}
+	var version int
+	if checkVersion {
+		if version, ok = s.claimVersion(c, shortURL, expected); !ok {
+			return
+		}
+	}
 
 	if err := s.store.DeleteShortURL(shortURL); err != nil {
 		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to delete short URL", nil)
 		return
 	}
+	recordHistory(s.store, c, models.LinkChangeDelete, nil, linkChange{before: link, after: link, version: version})
 	_ = s.cache.Delete(shortURL)
+	_ = s.cache.Delete(rotationKey(shortURL))
 	utility.WriteJSON(c.Writer, http.StatusOK, "Short URL deleted successfully", nil)
 }
 
 // @Summary      Get all short URLs
-// @Description  Fetches a list of all short URLs stored in the system
+// @Description  Fetches a list of all short URLs stored in the system, optionally filtered by tag or folder
 // @Tags         shortlinks
+// @Param        tag        query     string  false  "Only links with this tag"
+// @Param        folder_id  query     int     false  "Only links in this folder, 0 for links in no folder"
 // @Success      200        {array}   models.ShortURL  "Successfully fetched URLs"
+// @Failure      400        {string}  string  "Invalid tag or folder_id"
 // @Failure      500        {string}  string  "Failed to fetch URLs"
 // @Router       /api/v1/shortlinks [get]
 func (s *ShortlinkService) handleGetAllShortlinks(c *gin.Context) {
-	urls, err := s.store.GetAllShortURLs()
+	filter, err := parseLinkFilter(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	urls, err := s.store.GetShortURLs(filter)
 	if err != nil {
 		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch URLs", nil)
 		return
@@ -228,3 +661,28 @@ func (s *ShortlinkService) handleGetAllShortlinks(c *gin.Context) {
 
 	utility.WriteJSON(c.Writer, http.StatusOK, "Successfully fetched URLs", urls)
 }
+
+// @Summary      Get campaign statistics
+// @Description  Groups short URLs by their utm_campaign, utm_source and utm_medium and sums their access counts, optionally counting only links with a tag or in a folder
+// @Tags         shortlinks
+// @Produce      json
+// @Param        tag        query     string  false  "Only links with this tag"
+// @Param        folder_id  query     int     false  "Only links in this folder, 0 for links in no folder"
+// @Success      200        {array}   models.CampaignStats  "Successfully fetched campaign statistics"
+// @Failure      400        {string}  string  "Invalid tag or folder_id"
+// @Failure      500        {string}  string  "Failed to fetch campaign statistics"
+// @Router       /api/v1/shortlinks/campaigns [get]
+func (s *ShortlinkService) handleGetCampaignStats(c *gin.Context) {
+	filter, err := parseLinkFilter(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	stats, err := s.store.GetCampaignStats(filter)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch campaign statistics", nil)
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Successfully fetched campaign statistics", stats)
+}
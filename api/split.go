@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	variantCookieName = "kortlink_variant"
+	variantCookieTTL  = 30 * 24 * time.Hour
+
+	maxSplitVariants = 20
+	maxSplitWeight   = 10000
+)
+
+var variantNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,31}$`)
+
+func validateSplit(split *models.Split) error {
+	if split == nil || len(split.Variants) == 0 {
+		return nil
+	}
+	if len(split.Variants) < 2 || len(split.Variants) > maxSplitVariants {
+		return fmt.Errorf("split needs between 2 and %d variants", maxSplitVariants)
+	}
+	seen := make(map[string]bool, len(split.Variants))
+	for i, variant := range split.Variants {
+		if !variantNamePattern.MatchString(variant.Name) {
+			return fmt.Errorf("split.variants[%d]: name must be up to 32 letters, digits, '_' or '-'", i)
+		}
+		if seen[variant.Name] {
+			return fmt.Errorf("split.variants[%d]: duplicate name %q", i, variant.Name)
+		}
+		seen[variant.Name] = true
+		if variant.Weight < 1 || variant.Weight > maxSplitWeight {
+			return fmt.Errorf("split.variants[%d]: weight must be between 1 and %d", i, maxSplitWeight)
+		}
+		if err := utility.ValidateUrlRequest(variant.URL); err != nil {
+			return fmt.Errorf("split.variants[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// pickVariant chooses a variant at random, in proportion to its weight.
+func pickVariant(variants []models.SplitVariant) models.SplitVariant {
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+	n := rand.IntN(total)
+	for _, variant := range variants {
+		if n < variant.Weight {
+			return variant
+		}
+		n -= variant.Weight
+	}
+	return variants[len(variants)-1]
+}
+
+// selectVariant assigns the visitor a variant. Sticky splits remember the
+// assignment in a cookie scoped to the link, so returning visitors see the
+// same page; a cookie naming a variant that no longer exists is ignored.
+func selectVariant(c *gin.Context, split *models.Split) models.SplitVariant {
+	if split.Sticky {
+		if name, err := c.Cookie(variantCookieName); err == nil {
+			for _, variant := range split.Variants {
+				if variant.Name == name {
+					return variant
+				}
+			}
+		}
+	}
+
+	variant := pickVariant(split.Variants)
+	if split.Sticky {
+		secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(variantCookieName, variant.Name, int(variantCookieTTL.Seconds()), linkPath(c), "", secure, true)
+	}
+	return variant
+}
+
+// linkPath is the request path up to and including the slug, without any
+// passthrough path segments.
+func linkPath(c *gin.Context) string {
+	return strings.TrimSuffix(c.Request.URL.Path, c.GetString(extraPathKey))
+}
+
+// variantStats lists the link's current variants in order with their click
+// counts, followed by variants that were clicked but have since been removed.
+func variantStats(split *models.Split, clicks []models.VariantClicks) []models.VariantClicks {
+	counts := make(map[string]int, len(clicks))
+	for _, click := range clicks {
+		counts[click.Variant] = click.Clicks
+	}
+
+	var stats []models.VariantClicks
+	if split != nil {
+		for _, variant := range split.Variants {
+			stats = append(stats, models.VariantClicks{
+				Variant: variant.Name,
+				URL:     variant.URL,
+				Weight:  variant.Weight,
+				Clicks:  counts[variant.Name],
+			})
+			delete(counts, variant.Name)
+		}
+	}
+	for _, click := range clicks {
+		if _, removed := counts[click.Variant]; removed {
+			stats = append(stats, click)
+		}
+	}
+	return stats
+}
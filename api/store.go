@@ -2,20 +2,72 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"kortlink/internal/models"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrClickLimitReached is returned when a link has already served its
+// maximum number of redirects.
+var ErrClickLimitReached = errors.New("click limit reached")
+
+// ErrShortURLTaken is returned when a generated slug is already in use,
+// including by a link in the trash.
+var ErrShortURLTaken = errors.New("short URL is already taken")
+
+// ErrVersionMismatch is returned when a link has been changed since the
+// version a change was based on.
+var ErrVersionMismatch = errors.New("short URL has been changed")
+
 type Store interface {
 	CreateShortURL(shortURL *models.ShortURL) error
+	CreateShortURLs(shortURLs []*models.ShortURL) ([]error, error)
 	GetOriginalURL(shortURL string) (string, error)
 	IncrementAccessCount(shortURL string) error
 	UpdateShortURL(shortURL string, newOriginalURL string) error
 	DeleteShortURL(shortURL string) error
+	RestoreShortURL(shortURL string) error
+	PurgeDeletedShortURLs(cutoff time.Time) (int64, error)
+	DeleteShortURLs(filter models.LinkFilter) ([]string, error)
+	SetShortURLsDisabled(filter models.LinkFilter, disabled bool, reason string) ([]string, error)
+	SetShortURLsOriginalURL(filter models.LinkFilter, originalURL string, utm models.UTM) ([]string, error)
+	SetShortURLsTags(filter models.LinkFilter, tags []string) ([]string, error)
+	SetShortURLsFolder(filter models.LinkFilter, folderID *int) ([]string, error)
 	GetShortURLStats(shortURL string) (*models.ShortURL, error)
 	GetAllShortURLs() ([]models.ShortURL, error)
+	GetShortURLs(filter models.LinkFilter) ([]models.ShortURL, error)
+	GetShortURL(shortURL string) (*models.ShortURL, error)
+	SetShortURLDisabled(shortURL string, disabled bool, reason string) (int, error)
+	SearchShortURLs(query string, filter models.LinkFilter, limit, offset int) ([]models.SearchResult, error)
+	GetShortURLsWithoutMetadata(limit int) ([]models.ShortURL, error)
+	SetShortURLMetadata(shortURL string, originalURL string, meta models.Metadata) error
+	GetCampaignStats(filter models.LinkFilter) ([]models.CampaignStats, error)
+	RecordClick(shortURL string, click models.Click) error
+	GetClickCountries(shortURL string) ([]models.CountryClicks, error)
+	GetClickVariants(shortURL string) ([]models.VariantClicks, error)
+	CountClicksBySource(shortURL string, source string) (int, error)
+	GetDomainPolicies() ([]models.DomainPolicy, error)
+	CreateDomainPolicy(entry *models.DomainPolicy) error
+	DeleteDomainPolicy(id int) error
+	GetTags(filter models.LinkFilter) ([]models.Tag, error)
+	RenameTag(name string, newName string) ([]string, error)
+	DeleteTag(name string) ([]string, error)
+	GetFolders() ([]models.Folder, error)
+	GetFolder(id int) (*models.Folder, error)
+	CreateFolder(folder *models.Folder) error
+	RenameFolder(id int, name string) error
+	DeleteFolder(id int) ([]string, error)
+	ClaimShortURLVersion(shortURL string, version int) (int, error)
+	AddShortURLVersions(versions []*models.LinkVersion) error
+	GetShortURLVersions(shortURL string) ([]models.LinkVersion, error)
+	GetShortURLVersion(shortURL string, version int) (*models.LinkVersion, error)
+	SetShortURLState(shortURL string, state models.LinkState, expected *int) (int, error)
 }
 
 type Storage struct {
@@ -28,68 +80,516 @@ func NewStore(pool *pgxpool.Pool) *Storage {
 	}
 }
 
-func (s *Storage) CreateShortURL(shortURL *models.ShortURL) error {
-	query := `
-		INSERT INTO urls (original_url, short_url, access_count, created_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id;
-	`
-	err := s.pool.QueryRow(context.Background(), query,
+const insertShortURLQuery = `
+	INSERT INTO urls (original_url, short_url, access_count, password_hash, max_clicks, activate_at, deactivate_at, inactive_url, redirect_type, passthrough,
+		utm_source, utm_medium, utm_campaign, utm_term, utm_content, rules, targeting, split, rotation, interstitial, open_graph, folder_id, notes, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
+`
+
+func insertShortURLArgs(shortURL *models.ShortURL) []any {
+	return []any{
 		shortURL.OriginalURL,
 		shortURL.ShortURL,
 		shortURL.AccessCount,
+		shortURL.PasswordHash,
+		shortURL.MaxClicks,
+		shortURL.ActivateAt,
+		shortURL.DeactivateAt,
+		shortURL.InactiveURL,
+		shortURL.RedirectType,
+		shortURL.Passthrough,
+		shortURL.UTMSource,
+		shortURL.UTMMedium,
+		shortURL.UTMCampaign,
+		shortURL.UTMTerm,
+		shortURL.UTMContent,
+		shortURL.Rules,
+		shortURL.Targeting,
+		shortURL.Split,
+		shortURL.Rotation,
+		showsInterstitial(shortURL),
+		shortURL.OpenGraph,
+		shortURL.FolderID,
+		notesText(shortURL),
 		shortURL.CreatedAt,
-	).Scan(&shortURL.ID)
+	}
+}
+
+// insertURLTagsQuery tags the links with slugs $1 with the names in $2,
+// creating tags that do not exist yet.
+const insertURLTagsQuery = `
+	WITH t AS (
+		INSERT INTO tags (name) SELECT unnest($2::text[])
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+	)
+	INSERT INTO url_tags (url_id, tag_id)
+	SELECT u.id, t.id FROM urls u, t WHERE u.short_url = ANY($1)
+	ON CONFLICT DO NOTHING
+`
 
+func (s *Storage) CreateShortURL(shortURL *models.ShortURL) error {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := insertShortURLQuery + " ON CONFLICT (short_url) DO NOTHING RETURNING id"
+	err = tx.QueryRow(ctx, query, insertShortURLArgs(shortURL)...).Scan(&shortURL.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrShortURLTaken
+	}
 	if err != nil {
 		return fmt.Errorf("could not insert short URL: %w", err)
 	}
+	if len(shortURL.Tags) > 0 {
+		if _, err := tx.Exec(ctx, insertURLTagsQuery, []string{shortURL.ShortURL}, shortURL.Tags); err != nil {
+			return fmt.Errorf("could not tag short URL: %w", err)
+		}
+	}
 
-	return nil
+	return tx.Commit(ctx)
+}
+
+// CreateShortURLs inserts links in one transaction, sent as a single batch.
+// Links whose slug is already taken are skipped and reported as
+// ErrShortURLTaken in the returned slice, which has an entry per link; any
+// other error rolls back the whole batch.
+func (s *Storage) CreateShortURLs(shortURLs []*models.ShortURL) ([]error, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	query := insertShortURLQuery + " ON CONFLICT (short_url) DO NOTHING RETURNING id"
+	for _, shortURL := range shortURLs {
+		batch.Queue(query, insertShortURLArgs(shortURL)...)
+	}
+	results := tx.SendBatch(ctx, batch)
+	rowErrs := make([]error, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		err := results.QueryRow().Scan(&shortURL.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			rowErrs[i] = ErrShortURLTaken
+			continue
+		}
+		if err != nil {
+			results.Close()
+			return nil, fmt.Errorf("could not insert short URLs: %w", err)
+		}
+	}
+	if err := results.Close(); err != nil {
+		return nil, fmt.Errorf("could not insert short URLs: %w", err)
+	}
+
+	// Tags are added once the links exist, and only to links that were
+	// inserted: a skipped link's slug belongs to another link.
+	tagBatch := &pgx.Batch{}
+	for i, shortURL := range shortURLs {
+		if rowErrs[i] == nil && len(shortURL.Tags) > 0 {
+			tagBatch.Queue(insertURLTagsQuery, []string{shortURL.ShortURL}, shortURL.Tags)
+		}
+	}
+	if tagBatch.Len() > 0 {
+		if err := tx.SendBatch(ctx, tagBatch).Close(); err != nil {
+			return nil, fmt.Errorf("could not tag short URLs: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("could not insert short URLs: %w", err)
+	}
+	return rowErrs, nil
 }
 func (s *Storage) GetOriginalURL(shortURL string) (string, error) {
 	var originalURL string
-	query := `SELECT original_url FROM urls WHERE short_url = $1`
+	query := `SELECT original_url FROM urls WHERE short_url = $1 AND deleted_at IS NULL`
 	err := s.pool.QueryRow(context.Background(), query, shortURL).Scan(&originalURL)
 	if err != nil {
 		return "", err
 	}
 	return originalURL, nil
 }
+
+// IncrementAccessCount counts a visit. The limit check and the increment
+// happen in one statement, so concurrent visits cannot exceed max_clicks.
 func (s *Storage) IncrementAccessCount(shortURL string) error {
-	query := `UPDATE urls SET access_count = access_count + 1, updated_at = NOW() WHERE short_url = $1`
-	_, err := s.pool.Exec(context.Background(), query, shortURL)
-	return err
+	query := `
+		UPDATE urls
+		SET access_count = access_count + 1, updated_at = NOW()
+		WHERE short_url = $1 AND deleted_at IS NULL AND (max_clicks IS NULL OR access_count < max_clicks)
+	`
+	tag, err := s.pool.Exec(context.Background(), query, shortURL)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrClickLimitReached
+	}
+	return nil
 }
 func (s *Storage) UpdateShortURL(shortURL string, newOriginalURL string) error {
 	query := `
 		UPDATE urls
-		SET original_url = $1, updated_at = NOW()
+		SET original_url = $1, metadata_fetched_at = CASE WHEN original_url = $1 THEN metadata_fetched_at END, updated_at = NOW()
 		WHERE short_url = $2
 	`
 	_, err := s.pool.Exec(context.Background(), query, newOriginalURL, shortURL)
 	return err
 }
+
+// DeleteShortURL moves a link to the trash. The row, its clicks and its slug
+// are kept until PurgeDeletedShortURLs removes it.
 func (s *Storage) DeleteShortURL(shortURL string) error {
-	query := `DELETE FROM urls WHERE short_url = $1`
+	query := `UPDATE urls SET deleted_at = NOW() WHERE short_url = $1 AND deleted_at IS NULL`
 	_, err := s.pool.Exec(context.Background(), query, shortURL)
 	return err
 }
+
+// RestoreShortURL takes a link out of the trash. It returns pgx.ErrNoRows
+// when the link is not in the trash.
+func (s *Storage) RestoreShortURL(shortURL string) error {
+	query := `UPDATE urls SET deleted_at = NULL, updated_at = NOW() WHERE short_url = $1 AND deleted_at IS NOT NULL`
+	tag, err := s.pool.Exec(context.Background(), query, shortURL)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeDeletedShortURLs permanently removes links deleted before cutoff,
+// with their clicks and tags, and frees their slugs.
+func (s *Storage) PurgeDeletedShortURLs(cutoff time.Time) (int64, error) {
+	query := `DELETE FROM urls WHERE deleted_at < $1`
+	tag, err := s.pool.Exec(context.Background(), query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// linkFilterConditions turns filter into conditions over urls. Their
+// placeholders are numbered after the len(args) arguments already used by
+// the query, and their values are appended to args.
+func linkFilterConditions(filter models.LinkFilter, args []any) ([]string, []any) {
+	var conditions []string
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ShortURLs != nil {
+		add("urls.short_url = ANY($%d)", filter.ShortURLs)
+	}
+	if filter.CreatedAfter != nil {
+		add("urls.created_at >= $%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		add("urls.created_at < $%d", *filter.CreatedBefore)
+	}
+	if filter.UTMCampaign != "" {
+		add("urls.utm_campaign = $%d", filter.UTMCampaign)
+	}
+	if filter.Tag != "" {
+		add("EXISTS (SELECT 1 FROM url_tags ut JOIN tags t ON t.id = ut.tag_id WHERE ut.url_id = urls.id AND t.name = $%d)", filter.Tag)
+	}
+	if filter.FolderID != nil {
+		if *filter.FolderID == 0 {
+			conditions = append(conditions, "urls.folder_id IS NULL")
+		} else {
+			add("urls.folder_id = $%d", *filter.FolderID)
+		}
+	}
+	if filter.Owner != "" {
+		add("EXISTS (SELECT 1 FROM link_versions v WHERE v.url_id = urls.id AND v.action = '"+models.LinkChangeCreate+"' AND v.actor = $%d)", filter.Owner)
+	}
+	if filter.Deleted {
+		conditions = append(conditions, "urls.deleted_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "urls.deleted_at IS NULL")
+	}
+	return conditions, args
+}
+
+// linkFilterWhere is the WHERE clause of a bulk change. An empty filter
+// matches nothing rather than every link.
+func linkFilterWhere(filter models.LinkFilter, args []any) (string, []any) {
+	conditions, args := linkFilterConditions(filter, args)
+	if isEmptyLinkFilter(filter) {
+		return " WHERE FALSE", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// updateShortURLs runs a bulk UPDATE ending in RETURNING short_url and
+// returns the slugs it touched. A single statement either changes every
+// matching link or none, and it bumps their versions along with the change.
+func (s *Storage) updateShortURLs(query string, args ...any) ([]string, error) {
+	rows, err := s.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	shortURLs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if shortURLs == nil {
+		shortURLs = []string{}
+	}
+	return shortURLs, nil
+}
+
+func (s *Storage) DeleteShortURLs(filter models.LinkFilter) ([]string, error) {
+	where, args := linkFilterWhere(filter, nil)
+	return s.updateShortURLs(`UPDATE urls SET deleted_at = NOW(), version = version + 1`+where+` RETURNING short_url`, args...)
+}
+
+// SetShortURLsDisabled disables the matching links with reason, or enables
+// those that were disabled with reason, leaving links disabled for other
+// reasons alone.
+func (s *Storage) SetShortURLsDisabled(filter models.LinkFilter, disabled bool, reason string) ([]string, error) {
+	where, args := linkFilterWhere(filter, []any{disabled, reason})
+	query := `
+		UPDATE urls
+		SET disabled = $1, disabled_reason = CASE WHEN $1 THEN $2 ELSE '' END, version = version + 1, updated_at = NOW()
+	` + where + ` AND disabled = NOT $1 AND ($1 OR disabled_reason = $2)
+		RETURNING short_url`
+	return s.updateShortURLs(query, args...)
+}
+func (s *Storage) SetShortURLsOriginalURL(filter models.LinkFilter, originalURL string, utm models.UTM) ([]string, error) {
+	where, args := linkFilterWhere(filter, []any{originalURL, utm.UTMSource, utm.UTMMedium, utm.UTMCampaign, utm.UTMTerm, utm.UTMContent})
+	query := `
+		UPDATE urls
+		SET original_url = $1, utm_source = $2, utm_medium = $3, utm_campaign = $4, utm_term = $5, utm_content = $6,
+			metadata_fetched_at = CASE WHEN original_url = $1 THEN metadata_fetched_at END, version = version + 1, updated_at = NOW()
+	` + where + `
+		RETURNING short_url`
+	return s.updateShortURLs(query, args...)
+}
+
+// SetShortURLsTags replaces the tags of the matching links. Their versions
+// are bumped first, which also locks them for the duration of the change, so
+// concurrent re-tags of the same links do not interleave.
+func (s *Storage) SetShortURLsTags(filter models.LinkFilter, tags []string) ([]string, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	where, args := linkFilterWhere(filter, nil)
+	shortURLs, err := bumpLinkVersions(ctx, tx, `SELECT id FROM urls`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if shortURLs == nil {
+		shortURLs = []string{}
+	}
+
+	query := `DELETE FROM url_tags WHERE url_id IN (SELECT id FROM urls WHERE short_url = ANY($1))`
+	if _, err := tx.Exec(ctx, query, shortURLs); err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		if _, err := tx.Exec(ctx, insertURLTagsQuery, shortURLs, tags); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return shortURLs, nil
+}
+func (s *Storage) SetShortURLsFolder(filter models.LinkFilter, folderID *int) ([]string, error) {
+	where, args := linkFilterWhere(filter, []any{folderID})
+	return s.updateShortURLs(`UPDATE urls SET folder_id = $1, version = version + 1, updated_at = NOW()`+where+` RETURNING short_url`, args...)
+}
+
+// SearchShortURLs finds links whose title, slug, notes or destination match
+// query as words, or contain it as a fragment, as do links with a tag
+// containing it. Word matches rank above fragment matches, and title and
+// slug above notes, above the destination.
+func (s *Storage) SearchShortURLs(query string, filter models.LinkFilter, limit, offset int) ([]models.SearchResult, error) {
+	conditions, args := linkFilterConditions(filter, []any{query, "%" + escapeLike(query) + "%", limit, offset})
+	matches := `(
+		urls.search_vector @@ q
+		OR urls.title ILIKE $2 OR urls.short_url ILIKE $2 OR urls.notes ILIKE $2 OR urls.original_url ILIKE $2
+		OR EXISTS (SELECT 1 FROM url_tags ut JOIN tags t ON t.id = ut.tag_id WHERE ut.url_id = urls.id AND t.name ILIKE $2)
+	)`
+	sql := `
+		SELECT ` + shortURLColumns + `,
+			ts_rank_cd(urls.search_vector, q) + GREATEST(
+				word_similarity($1, urls.title), similarity($1, urls.short_url),
+				word_similarity($1, urls.notes) * 0.6, word_similarity($1, urls.original_url) * 0.4
+			) AS rank
+		FROM urls, websearch_to_tsquery('simple', $1) q
+		WHERE ` + strings.Join(append([]string{matches}, conditions...), " AND ") + `
+		ORDER BY rank DESC, urls.created_at DESC, urls.id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := s.pool.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var rank float64
+		url, err := scanShortURL(rows, &rank)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.SearchResult{ShortURL: *url, Rank: rank})
+	}
+	return results, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in s, so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
 func (s *Storage) GetShortURLStats(shortURL string) (*models.ShortURL, error) {
-	var url models.ShortURL
-	query := `SELECT original_url, short_url, access_count, created_at, updated_at FROM urls WHERE short_url = $1`
-	err := s.pool.QueryRow(context.Background(), query, shortURL).Scan(&url.OriginalURL, &url.ShortURL, &url.AccessCount, &url.CreatedAt, &url.UpdatedAt)
+	return s.GetShortURL(shortURL)
+}
+func (s *Storage) GetAllShortURLs() ([]models.ShortURL, error) {
+	return s.GetShortURLs(models.LinkFilter{})
+}
+
+// GetShortURLs lists the links matching filter; an empty filter lists all
+// of them.
+func (s *Storage) GetShortURLs(filter models.LinkFilter) ([]models.ShortURL, error) {
+	query := `SELECT ` + shortURLColumns + ` FROM urls`
+	conditions, args := linkFilterConditions(filter, nil)
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at, id`
+	rows, err := s.pool.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var urls []models.ShortURL
+	for rows.Next() {
+		url, err := scanShortURL(rows)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, *url)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// shortURLColumns lists the urls columns read by scanShortURL, in order.
+const shortURLColumns = `
+	id, original_url, short_url, version, access_count, disabled, disabled_reason, password_hash, max_clicks,
+	activate_at, deactivate_at, inactive_url, redirect_type, passthrough,
+	utm_source, utm_medium, utm_campaign, utm_term, utm_content, rules, targeting, split, rotation,
+	interstitial, open_graph, title, description, image_url, metadata_fetched_at, folder_id, notes, deleted_at,
+	COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM url_tags ut JOIN tags t ON t.id = ut.tag_id WHERE ut.url_id = urls.id), '{}'),
+	created_at, updated_at
+`
+
+// scanShortURL reads a row selected with shortURLColumns. Columns selected
+// after them are scanned into extra.
+func scanShortURL(row pgx.Row, extra ...any) (*models.ShortURL, error) {
+	var url models.ShortURL
+	dest := []any{
+		&url.ID,
+		&url.OriginalURL,
+		&url.ShortURL,
+		&url.Version,
+		&url.AccessCount,
+		&url.Disabled,
+		&url.DisabledReason,
+		&url.PasswordHash,
+		&url.MaxClicks,
+		&url.ActivateAt,
+		&url.DeactivateAt,
+		&url.InactiveURL,
+		&url.RedirectType,
+		&url.Passthrough,
+		&url.UTMSource,
+		&url.UTMMedium,
+		&url.UTMCampaign,
+		&url.UTMTerm,
+		&url.UTMContent,
+		&url.Rules,
+		&url.Targeting,
+		&url.Split,
+		&url.Rotation,
+		&url.Interstitial,
+		&url.OpenGraph,
+		&url.Title,
+		&url.Description,
+		&url.ImageURL,
+		&url.MetadataFetchedAt,
+		&url.FolderID,
+		&url.Notes,
+		&url.DeletedAt,
+		&url.Tags,
+		&url.CreatedAt,
+		&url.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	url.PasswordProtected = url.PasswordHash != ""
+	if len(url.Tags) == 0 {
+		url.Tags = nil
+	}
+	if url.Notes != nil && *url.Notes == "" {
+		url.Notes = nil
+	}
 	return &url, nil
 }
-func (s *Storage) GetAllShortURLs() ([]models.ShortURL, error) {
+
+func (s *Storage) GetShortURL(shortURL string) (*models.ShortURL, error) {
+	query := `SELECT ` + shortURLColumns + ` FROM urls WHERE short_url = $1 AND deleted_at IS NULL`
+	return scanShortURL(s.pool.QueryRow(context.Background(), query, shortURL))
+}
+
+// SetShortURLDisabled disables or re-enables a link and bumps its version
+// in the same statement. It returns the new version.
+func (s *Storage) SetShortURLDisabled(shortURL string, disabled bool, reason string) (int, error) {
 	query := `
-		SELECT short_url, original_url, access_count, created_at, updated_at
+		UPDATE urls
+		SET disabled = $1, disabled_reason = $2, version = version + 1, updated_at = NOW()
+		WHERE short_url = $3
+		RETURNING version
+	`
+	var version int
+	err := s.pool.QueryRow(context.Background(), query, disabled, reason, shortURL).Scan(&version)
+	return version, err
+}
+
+// GetShortURLsWithoutMetadata returns up to limit links, oldest first, whose
+// current destination has not been fetched yet. Changing original_url
+// clears metadata_fetched_at, so these are the links whose fetch is still
+// queued, was dropped or was never stored.
+func (s *Storage) GetShortURLsWithoutMetadata(limit int) ([]models.ShortURL, error) {
+	query := `
+		SELECT ` + shortURLColumns + `
 		FROM urls
+		WHERE metadata_fetched_at IS NULL AND deleted_at IS NULL
+		ORDER BY created_at, id
+		LIMIT $1
 	`
-	rows, err := s.pool.Query(context.Background(), query)
+	rows, err := s.pool.Query(context.Background(), query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -97,16 +597,575 @@ func (s *Storage) GetAllShortURLs() ([]models.ShortURL, error) {
 
 	var urls []models.ShortURL
 	for rows.Next() {
-		var url models.ShortURL
-		if err := rows.Scan(&url.ShortURL, &url.OriginalURL, &url.AccessCount, &url.CreatedAt, &url.UpdatedAt); err != nil {
+		url, err := scanShortURL(rows)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, *url)
+	}
+	return urls, rows.Err()
+}
+
+// SetShortURLMetadata stores metadata fetched for originalURL. It is a no-op
+// when the link has since been pointed elsewhere, so a slow fetch cannot
+// overwrite the metadata of a newer destination.
+func (s *Storage) SetShortURLMetadata(shortURL string, originalURL string, meta models.Metadata) error {
+	query := `
+		UPDATE urls
+		SET title = $1, description = $2, image_url = $3, metadata_fetched_at = NOW()
+		WHERE short_url = $4 AND original_url = $5
+	`
+	_, err := s.pool.Exec(context.Background(), query, meta.Title, meta.Description, meta.ImageURL, shortURL, originalURL)
+	return err
+}
+func (s *Storage) GetCampaignStats(filter models.LinkFilter) ([]models.CampaignStats, error) {
+	conditions, args := linkFilterConditions(filter, nil)
+	query := `
+		SELECT utm_campaign, utm_source, utm_medium, COUNT(*), COALESCE(SUM(access_count), 0)
+		FROM urls
+		WHERE ` + strings.Join(append([]string{"utm_campaign <> ''"}, conditions...), " AND ") + `
+		GROUP BY utm_campaign, utm_source, utm_medium
+		ORDER BY utm_campaign, utm_source, utm_medium
+	`
+	rows, err := s.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.CampaignStats
+	for rows.Next() {
+		var stat models.CampaignStats
+		if err := rows.Scan(&stat.UTMCampaign, &stat.UTMSource, &stat.UTMMedium, &stat.Links, &stat.AccessCount); err != nil {
 			return nil, err
 		}
-		urls = append(urls, url)
+		stats = append(stats, stat)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return urls, nil
+	return stats, nil
+}
+func (s *Storage) RecordClick(shortURL string, click models.Click) error {
+	query := `
+		INSERT INTO clicks (url_id, country, variant, source)
+		SELECT id, $2, $3, $4 FROM urls WHERE short_url = $1
+	`
+	_, err := s.pool.Exec(context.Background(), query, shortURL, click.Country, click.Variant, click.Source)
+	return err
+}
+func (s *Storage) GetClickCountries(shortURL string) ([]models.CountryClicks, error) {
+	query := `
+		SELECT c.country, COUNT(*)
+		FROM clicks c
+		JOIN urls u ON u.id = c.url_id
+		WHERE u.short_url = $1
+		GROUP BY c.country
+		ORDER BY COUNT(*) DESC, c.country
+	`
+	rows, err := s.pool.Query(context.Background(), query, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	countries := []models.CountryClicks{}
+	for rows.Next() {
+		var country models.CountryClicks
+		if err := rows.Scan(&country.Country, &country.Clicks); err != nil {
+			return nil, err
+		}
+		countries = append(countries, country)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return countries, nil
+}
+func (s *Storage) GetClickVariants(shortURL string) ([]models.VariantClicks, error) {
+	query := `
+		SELECT c.variant, COUNT(*)
+		FROM clicks c
+		JOIN urls u ON u.id = c.url_id
+		WHERE u.short_url = $1 AND c.variant <> ''
+		GROUP BY c.variant
+		ORDER BY c.variant
+	`
+	rows, err := s.pool.Query(context.Background(), query, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []models.VariantClicks
+	for rows.Next() {
+		var variant models.VariantClicks
+		if err := rows.Scan(&variant.Variant, &variant.Clicks); err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+func (s *Storage) CountClicksBySource(shortURL string, source string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM clicks c
+		JOIN urls u ON u.id = c.url_id
+		WHERE u.short_url = $1 AND c.source = $2
+	`
+	var count int
+	err := s.pool.QueryRow(context.Background(), query, shortURL, source).Scan(&count)
+	return count, err
+}
+func (s *Storage) GetDomainPolicies() ([]models.DomainPolicy, error) {
+	query := `
+		SELECT id, pattern, match_type, action, note, created_at
+		FROM domain_policies
+		ORDER BY id
+	`
+	rows, err := s.pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.DomainPolicy
+	for rows.Next() {
+		var entry models.DomainPolicy
+		if err := rows.Scan(&entry.ID, &entry.Pattern, &entry.MatchType, &entry.Action, &entry.Note, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+func (s *Storage) CreateDomainPolicy(entry *models.DomainPolicy) error {
+	query := `
+		INSERT INTO domain_policies (pattern, match_type, action, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at;
+	`
+	err := s.pool.QueryRow(context.Background(), query,
+		entry.Pattern,
+		entry.MatchType,
+		entry.Action,
+		entry.Note,
+	).Scan(&entry.ID, &entry.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("could not insert domain policy: %w", err)
+	}
+
+	return nil
+}
+func (s *Storage) DeleteDomainPolicy(id int) error {
+	query := `DELETE FROM domain_policies WHERE id = $1`
+	tag, err := s.pool.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetTags lists the tags in use on links matching filter, with their link
+// counts and combined access counts.
+func (s *Storage) GetTags(filter models.LinkFilter) ([]models.Tag, error) {
+	conditions, args := linkFilterConditions(filter, nil)
+	query := `
+		SELECT t.name, COUNT(*), COALESCE(SUM(urls.access_count), 0)
+		FROM tags t
+		JOIN url_tags ut ON ut.tag_id = t.id
+		JOIN urls ON urls.id = ut.url_id
+	`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` GROUP BY t.name ORDER BY t.name`
+	rows, err := s.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Links, &tag.AccessCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// bumpLinkVersions takes the next version of the links selected by the
+// url_ids subquery and returns their slugs, as part of tx. Changes made in
+// the same transaction are seen by readers together with the new version.
+func bumpLinkVersions(ctx context.Context, tx pgx.Tx, urlIDs string, args ...any) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		UPDATE urls SET version = version + 1, updated_at = NOW()
+		WHERE id IN (`+urlIDs+`)
+		RETURNING short_url`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// RenameTag renames a tag on every link. When newName already exists the
+// two tags are merged. It returns the links that carried the tag, whose
+// versions are bumped.
+func (s *Storage) RenameTag(name string, newName string) ([]string, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	if err := tx.QueryRow(ctx, `SELECT id FROM tags WHERE name = $1 FOR UPDATE`, name).Scan(&id); err != nil {
+		return nil, err
+	}
+	var targetID int
+	err = tx.QueryRow(ctx, `SELECT id FROM tags WHERE name = $1 FOR UPDATE`, newName).Scan(&targetID)
+	exists := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if exists && targetID == id {
+		return nil, nil
+	}
+	shortURLs, err := bumpLinkVersions(ctx, tx, `SELECT url_id FROM url_tags WHERE tag_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		_, err = tx.Exec(ctx, `UPDATE tags SET name = $1 WHERE id = $2`, newName, id)
+	} else {
+		query := `
+			INSERT INTO url_tags (url_id, tag_id)
+			SELECT url_id, $2 FROM url_tags WHERE tag_id = $1
+			ON CONFLICT DO NOTHING
+		`
+		if _, err = tx.Exec(ctx, query, id, targetID); err == nil {
+			_, err = tx.Exec(ctx, `DELETE FROM tags WHERE id = $1`, id)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return shortURLs, tx.Commit(ctx)
+}
+
+// DeleteTag removes a tag from every link and returns those links, whose
+// versions are bumped.
+func (s *Storage) DeleteTag(name string) ([]string, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	if err := tx.QueryRow(ctx, `SELECT id FROM tags WHERE name = $1 FOR UPDATE`, name).Scan(&id); err != nil {
+		return nil, err
+	}
+	shortURLs, err := bumpLinkVersions(ctx, tx, `SELECT url_id FROM url_tags WHERE tag_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM tags WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return shortURLs, tx.Commit(ctx)
+}
+
+// folderColumns lists the folder columns read by scanFolder, including the
+// number of links filed in it and their combined access count.
+const folderColumns = `
+	f.id, f.name, f.created_at,
+	(SELECT COUNT(*) FROM urls WHERE urls.folder_id = f.id AND urls.deleted_at IS NULL),
+	(SELECT COALESCE(SUM(access_count), 0) FROM urls WHERE urls.folder_id = f.id AND urls.deleted_at IS NULL)
+`
+
+func scanFolder(row pgx.Row) (*models.Folder, error) {
+	var folder models.Folder
+	if err := row.Scan(&folder.ID, &folder.Name, &folder.CreatedAt, &folder.Links, &folder.AccessCount); err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+func (s *Storage) GetFolders() ([]models.Folder, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT `+folderColumns+` FROM folders f ORDER BY f.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, *folder)
+	}
+	return folders, rows.Err()
+}
+func (s *Storage) GetFolder(id int) (*models.Folder, error) {
+	return scanFolder(s.pool.QueryRow(context.Background(), `SELECT `+folderColumns+` FROM folders f WHERE f.id = $1`, id))
+}
+func (s *Storage) CreateFolder(folder *models.Folder) error {
+	query := `INSERT INTO folders (name) VALUES ($1) RETURNING id, created_at`
+	err := s.pool.QueryRow(context.Background(), query, folder.Name).Scan(&folder.ID, &folder.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("could not insert folder: %w", err)
+	}
+	return nil
+}
+func (s *Storage) RenameFolder(id int, name string) error {
+	tag, err := s.pool.Exec(context.Background(), `UPDATE folders SET name = $1 WHERE id = $2`, name, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteFolder deletes a folder and returns the links that were filed in
+// it, which are left unfiled and whose versions are bumped.
+func (s *Storage) DeleteFolder(id int) ([]string, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked int
+	if err := tx.QueryRow(ctx, `SELECT id FROM folders WHERE id = $1 FOR UPDATE`, id).Scan(&locked); err != nil {
+		return nil, err
+	}
+	shortURLs, err := bumpLinkVersions(ctx, tx, `SELECT id FROM urls WHERE folder_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM folders WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return shortURLs, tx.Commit(ctx)
+}
+
+// ClaimShortURLVersion takes the next version of a link for a change based
+// on version. The check and the increment happen in one statement, so of
+// two changes based on the same version only one is let through. It returns
+// the claimed version, or the current one with ErrVersionMismatch, and
+// pgx.ErrNoRows when the link does not exist.
+func (s *Storage) ClaimShortURLVersion(shortURL string, version int) (int, error) {
+	ctx := context.Background()
+	query := `
+		UPDATE urls
+		SET version = version + 1
+		WHERE short_url = $1 AND deleted_at IS NULL AND version = $2
+		RETURNING version
+	`
+	var claimed int
+	err := s.pool.QueryRow(ctx, query, shortURL, version).Scan(&claimed)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return claimed, err
+	}
+
+	var current int
+	query = `SELECT version FROM urls WHERE short_url = $1 AND deleted_at IS NULL`
+	if err := s.pool.QueryRow(ctx, query, shortURL).Scan(&current); err != nil {
+		return 0, err
+	}
+	return current, ErrVersionMismatch
+}
+
+// AddShortURLVersions appends an entry to the history of each link, sent
+// as one batch. Entries with a version already taken for the change, by
+// ClaimShortURLVersion or SetShortURLState, are stored under it; the others
+// are numbered by bumping their link's version. The version and time of
+// each entry are set on it. Entries for links that no longer exist are
+// skipped.
+func (s *Storage) AddShortURLVersions(versions []*models.LinkVersion) error {
+	query := `
+		WITH u AS (
+			UPDATE urls SET version = CASE WHEN $8 > 0 THEN GREATEST(version, $8) ELSE version + 1 END
+			WHERE short_url = $1
+			RETURNING id, version
+		)
+		INSERT INTO link_versions (url_id, version, action, actor, ip, changes, state, rolled_back_to)
+		SELECT id, CASE WHEN $8 > 0 THEN $8 ELSE version END, $2, $3, $4, $5, $6, $7 FROM u
+		RETURNING version, created_at
+	`
+	batch := &pgx.Batch{}
+	for _, v := range versions {
+		changes := v.Changes
+		if changes == nil {
+			changes = map[string]models.FieldChange{}
+		}
+		batch.Queue(query, v.ShortURL, v.Action, v.Actor, v.IP, changes, v.State, v.RolledBackTo, v.Version)
+	}
+	results := s.pool.SendBatch(context.Background(), batch)
+	for _, v := range versions {
+		err := results.QueryRow().Scan(&v.Version, &v.CreatedAt)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			results.Close()
+			return err
+		}
+	}
+	return results.Close()
+}
+
+// GetShortURLVersions returns the history of a link, newest first.
+func (s *Storage) GetShortURLVersions(shortURL string) ([]models.LinkVersion, error) {
+	query := `
+		SELECT v.version, v.action, v.actor, v.ip, v.changes, v.rolled_back_to, v.created_at
+		FROM link_versions v JOIN urls u ON u.id = v.url_id
+		WHERE u.short_url = $1 AND u.deleted_at IS NULL
+		ORDER BY v.version DESC
+	`
+	rows, err := s.pool.Query(context.Background(), query, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.LinkVersion{}
+	for rows.Next() {
+		var v models.LinkVersion
+		if err := rows.Scan(&v.Version, &v.Action, &v.Actor, &v.IP, &v.Changes, &v.RolledBackTo, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(v.Changes) == 0 {
+			v.Changes = nil
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// GetShortURLVersion returns one entry of a link's history with the state
+// it recorded, or pgx.ErrNoRows.
+func (s *Storage) GetShortURLVersion(shortURL string, version int) (*models.LinkVersion, error) {
+	query := `
+		SELECT v.version, v.action, v.actor, v.ip, v.changes, v.state, v.rolled_back_to, v.created_at
+		FROM link_versions v JOIN urls u ON u.id = v.url_id
+		WHERE u.short_url = $1 AND u.deleted_at IS NULL AND v.version = $2
+	`
+	v := models.LinkVersion{ShortURL: shortURL}
+	err := s.pool.QueryRow(context.Background(), query, shortURL, version).
+		Scan(&v.Version, &v.Action, &v.Actor, &v.IP, &v.Changes, &v.State, &v.RolledBackTo, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// SetShortURLState overwrites the configuration of a link with state and
+// takes its next version, in one transaction. When expected is set, the
+// change is only made if the link is still at that version; otherwise the
+// current version is returned with ErrVersionMismatch. A folder that has
+// since been deleted leaves the link unfiled. It returns the new version,
+// or pgx.ErrNoRows when the link does not exist.
+func (s *Storage) SetShortURLState(shortURL string, state models.LinkState, expected *int) (int, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE urls
+		SET original_url = $1, utm_source = $2, utm_medium = $3, utm_campaign = $4, utm_term = $5, utm_content = $6,
+			password_hash = $7, redirect_type = $8, passthrough = $9, rules = $10, targeting = $11, split = $12,
+			rotation = $13, interstitial = $14, open_graph = $15, max_clicks = $16, activate_at = $17,
+			deactivate_at = $18, inactive_url = $19, folder_id = (SELECT id FROM folders WHERE id = $20),
+			notes = $21, disabled = $22, disabled_reason = $23,
+			metadata_fetched_at = CASE WHEN original_url = $1 THEN metadata_fetched_at END,
+			version = version + 1, updated_at = NOW()
+		WHERE short_url = $24 AND deleted_at IS NULL AND ($25::INT IS NULL OR version = $25)
+		RETURNING version
+	`
+	var version int
+	err = tx.QueryRow(ctx, query,
+		state.OriginalURL,
+		state.UTMSource,
+		state.UTMMedium,
+		state.UTMCampaign,
+		state.UTMTerm,
+		state.UTMContent,
+		state.PasswordHash,
+		state.RedirectType,
+		state.Passthrough,
+		state.Rules,
+		state.Targeting,
+		state.Split,
+		state.Rotation,
+		state.Interstitial,
+		state.OpenGraph,
+		state.MaxClicks,
+		state.ActivateAt,
+		state.DeactivateAt,
+		state.InactiveURL,
+		state.FolderID,
+		state.Notes,
+		state.Disabled,
+		state.DisabledReason,
+		shortURL,
+		expected,
+	).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) && expected != nil {
+		query = `SELECT version FROM urls WHERE short_url = $1 AND deleted_at IS NULL`
+		if err := tx.QueryRow(ctx, query, shortURL).Scan(&version); err != nil {
+			return 0, err
+		}
+		return version, ErrVersionMismatch
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	query = `DELETE FROM url_tags WHERE url_id = (SELECT id FROM urls WHERE short_url = $1)`
+	if _, err := tx.Exec(ctx, query, shortURL); err != nil {
+		return 0, err
+	}
+	if len(state.Tags) > 0 {
+		if _, err := tx.Exec(ctx, insertURLTagsQuery, []string{shortURL}, state.Tags); err != nil {
+			return 0, err
+		}
+	}
+	return version, tx.Commit(ctx)
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation,
+// such as a folder name that is already taken.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"kortlink/internal/cache"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxTagsPerLink = 20
+	maxTagLength   = 50
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]*$`)
+
+// normalizeTag lower-cases a tag name and turns spaces into dashes, so
+// "Spring Sale" and "spring-sale" are the same tag.
+func normalizeTag(name string) (string, error) {
+	tag := strings.Join(strings.Fields(strings.ToLower(name)), "-")
+	if len(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("tag %q must be up to %d characters of letters, digits, '_', '.', ':' or '-'", name, maxTagLength)
+	}
+	return tag, nil
+}
+
+// normalizeTags normalizes and de-duplicates a link's tags, keeping their
+// order. A nil list stays nil, so updates can tell "keep" from "clear".
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, name := range tags {
+		tag, err := normalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxTagsPerLink {
+		return nil, fmt.Errorf("a link can have at most %d tags", maxTagsPerLink)
+	}
+	return normalized, nil
+}
+
+// checkFolder returns an error when folderID does not name an existing
+// folder. Nil and 0 mean no folder and are always valid.
+func checkFolder(store Store, folderID *int) error {
+	if folderID == nil || *folderID == 0 {
+		return nil
+	}
+	if _, err := store.GetFolder(*folderID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("folder %d does not exist", *folderID)
+		}
+		return err
+	}
+	return nil
+}
+
+// knownFolders reads the folders once and returns a check like checkFolder
+// against them, for requests that validate many links.
+func knownFolders(store Store) (func(folderID *int) error, error) {
+	folders, err := store.GetFolders()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int]bool, len(folders))
+	for _, folder := range folders {
+		ids[folder.ID] = true
+	}
+	return func(folderID *int) error {
+		if folderID == nil || *folderID == 0 || ids[*folderID] {
+			return nil
+		}
+		return fmt.Errorf("folder %d does not exist", *folderID)
+	}, nil
+}
+
+// parseLinkFilter reads the tag and folder_id query parameters shared by
+// the list and stats endpoints. folder_id=0 selects links in no folder.
+func parseLinkFilter(c *gin.Context) (models.LinkFilter, error) {
+	var filter models.LinkFilter
+	if tag := c.Query("tag"); tag != "" {
+		normalized, err := normalizeTag(tag)
+		if err != nil {
+			return filter, err
+		}
+		filter.Tag = normalized
+	}
+	if folder := c.Query("folder_id"); folder != "" {
+		id, err := strconv.Atoi(folder)
+		if err != nil || id < 0 {
+			return filter, errors.New("folder_id must be a folder ID, or 0 for links in no folder")
+		}
+		filter.FolderID = &id
+	}
+	return filter, nil
+}
+
+type TagService struct {
+	store Store
+	cache *cache.RedisCache
+}
+
+func NewTagService(s Store, c *cache.RedisCache) *TagService {
+	return &TagService{store: s, cache: c}
+}
+
+func (s *TagService) TagRoutes(r *gin.RouterGroup) {
+	r.GET("/tags", s.handleGetTags)
+	r.PUT("/tags/:name", s.handleRenameTag)
+	r.DELETE("/tags/:name", s.handleDeleteTag)
+}
+
+// @Summary      List tags
+// @Description  Lists the tags in use with the number of links carrying each and their combined access count. Filter by folder_id to count only the links in a folder (0 for links in no folder).
+// @Tags         tags
+// @Produce      json
+// @Param        folder_id  query     int  false  "Only count links in this folder"
+// @Success      200        {array}   models.Tag
+// @Failure      400        {object}  models.Response
+// @Failure      500        {object}  models.Response
+// @Router       /api/v1/tags [get]
+func (s *TagService) handleGetTags(c *gin.Context) {
+	filter, err := parseLinkFilter(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	tags, err := s.store.GetTags(filter)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch tags", nil)
+		return
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Successfully fetched tags", tags)
+}
+
+// @Summary      Rename tag
+// @Description  Renames a tag on every link. Renaming to an existing tag merges the two. Each link that carried the tag gets a new version, recorded in its history.
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string             true  "Tag name"
+// @Param        body  body      models.TagPayload  true  "New name"
+// @Success      200   {object}  models.Response
+// @Failure      400   {object}  models.Response
+// @Failure      404   {object}  models.Response
+// @Failure      500   {object}  models.Response
+// @Router       /api/v1/tags/{name} [put]
+func (s *TagService) handleRenameTag(c *gin.Context) {
+	var payload models.TagPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, "Invalid request payload", nil)
+		return
+	}
+	newName, err := normalizeTag(payload.Name)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	name := c.Param("name")
+	before, err := allLinks(s.store, models.LinkFilter{Tag: name})
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to rename tag", nil)
+		return
+	}
+	shortURLs, err := s.store.RenameTag(name, newName)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Tag not found", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to rename tag", nil)
+		return
+	}
+	recordBumpedHistory(s.store, c, models.LinkChangeTag, before, shortURLs)
+	_ = s.cache.DeleteKeys(shortURLs)
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Tag renamed successfully", gin.H{"name": newName})
+}
+
+// @Summary      Delete tag
+// @Description  Removes a tag from every link. Each of them gets a new version, recorded in its history.
+// @Tags         tags
+// @Produce      json
+// @Param        name  path      string  true  "Tag name"
+// @Success      200   {object}  models.Response
+// @Failure      404   {object}  models.Response
+// @Failure      500   {object}  models.Response
+// @Router       /api/v1/tags/{name} [delete]
+func (s *TagService) handleDeleteTag(c *gin.Context) {
+	name := c.Param("name")
+	before, err := allLinks(s.store, models.LinkFilter{Tag: name})
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to delete tag", nil)
+		return
+	}
+	shortURLs, err := s.store.DeleteTag(name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Tag not found", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to delete tag", nil)
+		return
+	}
+	recordBumpedHistory(s.store, c, models.LinkChangeTag, before, shortURLs)
+	_ = s.cache.DeleteKeys(shortURLs)
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Tag deleted successfully", nil)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"kortlink/internal/geoip"
+	"kortlink/internal/models"
+	"kortlink/internal/useragent"
+	"kortlink/internal/utility"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// validateTargeting checks every rule and upper-cases its country and
+// continent codes in place.
+func validateTargeting(rules []models.TargetingRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+		rule.Continent = strings.ToUpper(strings.TrimSpace(rule.Continent))
+
+		if rule.OS == "" && rule.Device == "" && rule.Browser == "" && rule.Country == "" && rule.Continent == "" {
+			return fmt.Errorf("targeting[%d] needs at least one of os, device, browser, country or continent", i)
+		}
+		if rule.OS != "" && !slices.Contains(useragent.OSes, rule.OS) {
+			return fmt.Errorf("targeting[%d]: unknown os %q", i, rule.OS)
+		}
+		if rule.Device != "" && !slices.Contains(useragent.Devices, rule.Device) {
+			return fmt.Errorf("targeting[%d]: unknown device %q", i, rule.Device)
+		}
+		if rule.Browser != "" && !slices.Contains(useragent.Browsers, rule.Browser) {
+			return fmt.Errorf("targeting[%d]: unknown browser %q", i, rule.Browser)
+		}
+		if rule.Country != "" && !countryCodePattern.MatchString(rule.Country) {
+			return fmt.Errorf("targeting[%d]: country must be a two-letter ISO 3166-1 code, got %q", i, rule.Country)
+		}
+		if rule.Continent != "" && !slices.Contains(geoip.Continents, rule.Continent) {
+			return fmt.Errorf("targeting[%d]: unknown continent %q", i, rule.Continent)
+		}
+		if err := utility.ValidateUrlRequest(rule.URL); err != nil {
+			return fmt.Errorf("targeting[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// matchTargeting returns the URL of the first rule matching the visitor.
+func matchTargeting(rules []models.TargetingRule, v visitor) (string, bool) {
+	for _, rule := range rules {
+		if rule.OS != "" && rule.OS != v.OS {
+			continue
+		}
+		if rule.Device != "" && rule.Device != v.Device {
+			continue
+		}
+		if rule.Browser != "" && rule.Browser != v.Browser {
+			continue
+		}
+		if rule.Country != "" && rule.Country != v.Country {
+			continue
+		}
+		if rule.Continent != "" && rule.Continent != v.Continent {
+			continue
+		}
+		return rule.URL, true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"kortlink/internal/config"
+	"kortlink/internal/models"
+	"kortlink/internal/utility"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// startTrashPurge permanently removes links that have been in the trash for
+// longer than retention, once at start and then every interval.
+func startTrashPurge(store Store, retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+	purge := func() {
+		purged, err := store.PurgeDeletedShortURLs(time.Now().Add(-retention))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to purge deleted short URLs")
+			return
+		}
+		if purged > 0 {
+			log.Info().Int64("purged", purged).Msg("Purged deleted short URLs")
+		}
+	}
+	go func() {
+		purge()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
+
+// @Summary      List deleted short URLs
+// @Description  Lists the short URLs in the trash with when they were deleted and when they will be purged, optionally filtered by tag or folder
+// @Tags         shortlinks
+// @Produce      json
+// @Param        tag        query     string  false  "Only links with this tag"
+// @Param        folder_id  query     int     false  "Only links in this folder, 0 for links in no folder"
+// @Success      200        {array}   models.ShortURL
+// @Failure      400        {object}  models.Response
+// @Failure      500        {object}  models.Response
+// @Router       /api/v1/shortlinks/trash [get]
+func (s *ShortlinkService) handleGetTrash(c *gin.Context) {
+	filter, err := parseLinkFilter(c)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	filter.Deleted = true
+	urls, err := s.store.GetShortURLs(filter)
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to fetch deleted URLs", nil)
+		return
+	}
+	if urls == nil {
+		urls = []models.ShortURL{}
+	}
+	for i := range urls {
+		if urls[i].DeletedAt != nil && config.Envs.TrashRetention > 0 {
+			purgeAt := urls[i].DeletedAt.Add(config.Envs.TrashRetention)
+			urls[i].PurgeAt = &purgeAt
+		}
+	}
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Successfully fetched deleted URLs", urls)
+}
+
+// @Summary      Restore a deleted short URL
+// @Description  Takes a short URL out of the trash, so it redirects again with its settings, tags and click history intact. Its destinations are checked against the current domain policy and threat lists first; a link the domain policy disabled is restored disabled.
+// @Tags         shortlinks
+// @Produce      json
+// @Param        shortURL   path      string  true  "Short URL"
+// @Success      200        {object}  models.Response
+// @Failure      400        {object}  models.Response
+// @Failure      404        {object}  models.Response
+// @Failure      500        {object}  models.Response
+// @Router       /api/v1/{shortURL}/restore [post]
+func (s *ShortlinkService) handleRestoreShortlink(c *gin.Context) {
+	shortURL := c.Param("shortURL")
+	trashed, err := s.store.GetShortURLs(models.LinkFilter{ShortURLs: []string{shortURL}, Deleted: true})
+	if err != nil {
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to restore short URL", nil)
+		return
+	}
+	if len(trashed) == 0 {
+		utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found in the trash", nil)
+		return
+	}
+	// The policy and threat lists may have changed since the link was
+	// deleted, and the policy is not enforced on the trash. A link the policy
+	// already disabled comes back disabled, and is enabled again by the
+	// policy once it passes.
+	if link := &trashed[0]; !(link.Disabled && link.DisabledReason == models.DisabledReasonDomainPolicy) &&
+		!s.checkDestinations(c, linkDestinations(link)...) {
+		return
+	}
+	if err := s.store.RestoreShortURL(shortURL); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utility.WriteJSON(c.Writer, http.StatusNotFound, "Short URL not found in the trash", nil)
+			return
+		}
+		utility.WriteJSON(c.Writer, http.StatusInternalServerError, "Failed to restore short URL", nil)
+		return
+	}
+	if link, err := s.store.GetShortURL(shortURL); err == nil {
+		recordHistory(s.store, c, models.LinkChangeRestore, nil, linkChange{before: link, after: link})
+	}
+	_ = s.cache.Delete(shortURL)
+
+	utility.WriteJSON(c.Writer, http.StatusOK, "Short URL restored successfully", nil)
+}
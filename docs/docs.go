@@ -15,9 +15,36 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/shortlink": {
+        "/api/v1/admin/domain-policies": {
+            "get": {
+                "description": "Lists the allow and block entries checked against link destinations",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List domain policies",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DomainPolicy"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Create a new short URL",
+                "description": "Adds an exact, wildcard-subdomain or regex allow/block entry and disables existing links that now violate the policy",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,17 +52,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "shortlinks"
+                    "admin"
                 ],
-                "summary": "Create Shortlink",
+                "summary": "Create domain policy",
                 "parameters": [
                     {
-                        "description": "Original URL payload",
+                        "description": "Domain policy entry",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ShortURLPayload"
+                            "$ref": "#/definitions/models.DomainPolicyPayload"
                         }
                     }
                 ],
@@ -43,7 +70,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.ShortURL"
+                            "$ref": "#/definitions/models.DomainPolicy"
                         }
                     },
                     "400": {
@@ -61,77 +88,134 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/shortlinks": {
+        "/api/v1/admin/domain-policies/{id}": {
+            "delete": {
+                "description": "Removes a domain policy entry and re-enables links that no longer violate the policy",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete domain policy",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Domain policy ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/folders": {
             "get": {
-                "description": "Fetches a list of all short URLs stored in the system",
+                "description": "Lists folders with the number of links filed in each and their combined access count",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "shortlinks"
+                    "folders"
                 ],
-                "summary": "Get all short URLs",
+                "summary": "List folders",
                 "responses": {
                     "200": {
-                        "description": "Successfully fetched URLs",
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.ShortURL"
+                                "$ref": "#/definitions/models.Folder"
                             }
                         }
                     },
                     "500": {
-                        "description": "Failed to fetch URLs",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/{shortURL}": {
-            "get": {
-                "description": "Redirects to the original URL based on the provided short URL",
+            },
+            "post": {
+                "description": "Creates a folder to file links in. Folder names are unique.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "shortlinks"
+                    "folders"
                 ],
-                "summary": "Redirect to the original URL",
+                "summary": "Create folder",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Short URL",
-                        "name": "shortURL",
-                        "in": "path",
-                        "required": true
+                        "description": "Folder",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FolderPayload"
+                        }
                     }
                 ],
                 "responses": {
-                    "302": {
-                        "description": "Redirected to the original URL",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Folder"
                         }
                     },
                     "400": {
-                        "description": "Short URL is required",
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     },
-                    "404": {
-                        "description": "Short URL not found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     },
                     "500": {
-                        "description": "Failed to update access count",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/v1/folders/{id}": {
             "put": {
-                "description": "Update the original URL for a given short URL",
                 "consumes": [
                     "application/json"
                 ],
@@ -139,172 +223,1974 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "shortlinks"
+                    "folders"
                 ],
-                "summary": "Update a short URL",
+                "summary": "Rename folder",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Short URL",
-                        "name": "shortURL",
+                        "type": "integer",
+                        "description": "Folder ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "New original URL",
+                        "description": "New name",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ShortURL"
+                            "$ref": "#/definitions/models.FolderPayload"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Short URL updated successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     },
                     "400": {
-                        "description": "Invalid request payload or Short URL is required",
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     },
                     "404": {
-                        "description": "Short URL not found",
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
                         }
                     },
                     "500": {
-                        "description": "Failed to update short URL",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Deletes a given short URL and its related data",
+                "description": "Deletes a folder. Its links are kept and no longer filed in a folder; each of them gets a new version, recorded in its history.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "shortlinks"
+                    "folders"
                 ],
-                "summary": "Delete a short URL",
+                "summary": "Delete folder",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Short URL",
-                        "name": "shortURL",
+                        "type": "integer",
+                        "description": "Folder ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Short URL deleted successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     },
                     "400": {
-                        "description": "Short URL is required",
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     },
                     "404": {
-                        "description": "Short URL not found",
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     },
                     "500": {
-                        "description": "Failed to delete short URL",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Response"
                         }
                     }
                 }
             }
         },
-        "/api/v1/{shortURL}/stats": {
+        "/api/v1/shortlink": {
+            "post": {
+                "description": "Create a new short URL",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Create Shortlink",
+                "parameters": [
+                    {
+                        "description": "Original URL payload",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ShortURLPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ShortURL"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shortlinks": {
             "get": {
-                "description": "Fetches the statistics (e.g., access count) for a given short URL",
+                "description": "Fetches a list of all short URLs stored in the system, optionally filtered by tag or folder",
                 "tags": [
                     "shortlinks"
                 ],
-                "summary": "Get short URL statistics",
+                "summary": "Get all short URLs",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Short URL",
-                        "name": "shortURL",
-                        "in": "path",
-                        "required": true
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only links in this folder, 0 for links in no folder",
+                        "name": "folder_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Statistics fetched successfully",
+                        "description": "Successfully fetched URLs",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ShortURL"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Short URL is required",
+                        "description": "Invalid tag or folder_id",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Short URL not found",
+                    "500": {
+                        "description": "Failed to fetch URLs",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Response": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "description": "Data is omitted if nil or empty"
-                },
-                "message": {
-                    "type": "string"
-                },
-                "statusCode": {
-                    "type": "integer"
-                }
-            }
         },
-        "models.ShortURL": {
-            "type": "object",
-            "properties": {
+        "/api/v1/shortlinks/bulk": {
+            "post": {
+                "description": "Creates up to 10,000 short URLs from a JSON array of link payloads (as for POST /shortlink), a CSV body or a CSV file uploaded in the file field. CSV files start with a header naming the columns: original_url, password, max_clicks, redirect_type, activate_at, deactivate_at, inactive_url, interstitial, tags (separated by semicolons), folder_id, notes and utm_*. At most 200 rows per request can set a password. Every row is validated on its own and the valid rows are inserted in one transaction; the response has a result per row, with the created link or the row's error.",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Create short URLs in bulk",
+                "parameters": [
+                    {
+                        "description": "Links to create",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ShortURLPayload"
+                            }
+                        }
+                    },
+                    {
+                        "type": "file",
+                        "description": "CSV file",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BulkCreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shortlinks/bulk/actions": {
+            "post": {
+                "description": "Applies an action to every short URL matching the filter: delete, disable, enable (links disabled by this endpoint only), repoint to a new original_url, retag (replace the tags with tags) or move to to_folder_id (0 for no folder). Filter by a list of short_urls, a created_after/created_before range, utm_campaign, tag, folder_id and owner; set fields are combined and at least one is required. Links have no owner field: owner matches the X-Actor recorded when the link was created, so links created without one, or before history was kept, have no owner. The change is made in one statement, so it applies to all matching links or none, and their cache entries are cleared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Update or delete short URLs in bulk",
+                "parameters": [
+                    {
+                        "description": "Action and filter",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BulkActionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BulkActionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shortlinks/campaigns": {
+            "get": {
+                "description": "Groups short URLs by their utm_campaign, utm_source and utm_medium and sums their access counts, optionally counting only links with a tag or in a folder",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Get campaign statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only links in this folder, 0 for links in no folder",
+                        "name": "folder_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully fetched campaign statistics",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CampaignStats"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tag or folder_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to fetch campaign statistics",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shortlinks/search": {
+            "get": {
+                "description": "Full-text search over the destination URL, slug, title, notes and tags. Words match anywhere in these fields, and so do fragments such as part of a path; quoted phrases, OR and -word are supported. Results are ranked with title and slug matches first, and each carries its matching fields with the terms wrapped in \u003cmark\u003e tags (the text is HTML-escaped).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Search short URLs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only links in this folder, 0 for links in no folder",
+                        "name": "folder_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Results per page, 1-100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shortlinks/trash": {
+            "get": {
+                "description": "Lists the short URLs in the trash with when they were deleted and when they will be purged, optionally filtered by tag or folder",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "List deleted short URLs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only links in this folder, 0 for links in no folder",
+                        "name": "folder_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ShortURL"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tags": {
+            "get": {
+                "description": "Lists the tags in use with the number of links carrying each and their combined access count. Filter by folder_id to count only the links in a folder (0 for links in no folder).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "List tags",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Only count links in this folder",
+                        "name": "folder_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Tag"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tags/{name}": {
+            "put": {
+                "description": "Renames a tag on every link. Renaming to an existing tag merges the two. Each link that carried the tag gets a new version, recorded in its history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Rename tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New name",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TagPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes a tag from every link. Each of them gets a new version, recorded in its history.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Delete tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/{shortURL}": {
+            "get": {
+                "description": "Redirects to the original URL based on the provided short URL",
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Redirect to the original URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to 1 to skip the threat warning and interstitial pages",
+                        "name": "proceed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to 1 (or append + to the short URL) to show the preview page instead of redirecting",
+                        "name": "preview",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Preview page, interstitial page, Open Graph page for link preview crawlers or warning page for a flagged destination",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "302": {
+                        "description": "Redirected to the original URL (301, 307 or 308 when the link's redirect_type says so)",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the link, for If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Short URL is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Password form for a protected link",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL is not active yet",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "Short URL is disabled, expired or has reached its click limit",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update access count",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update the original URL for a given short URL. If-Match must carry the ETag the link was read with (or \"*\" to overwrite whatever is there); if the link has changed since, nothing is updated and 412 is returned with the current ETag.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Update a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the update is based on",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "New original URL",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ShortURL"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Short URL updated successfully",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated link"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or Short URL is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Short URL has been changed since the version in If-Match",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update short URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Verifies the password submitted from the unlock form, sets a signed cookie scoped to the short URL and redirects back to it. POSTs to links without a password are redirected like GETs.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Unlock a password-protected short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Link password",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "Redirected back to the short URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Incorrect password",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves a short URL to the trash. It stops redirecting at once and can be restored until it is purged, after TRASH_RETENTION (30 days by default); its slug is not reissued in the meantime.",
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Delete a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the delete is based on, or *",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Short URL deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Short URL is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Short URL has been changed since the version in If-Match",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete short URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Applies a JSON Merge Patch (RFC 7396) to a short URL, so only the fields to change are sent: a value sets a field, null clears it and objects such as passthrough or open_graph are merged. Arrays such as tags and rules are replaced as a whole. password sets a new password, and null or \"\" removes it. All changes are validated together and applied at once; invalid fields are reported individually in data.errors. If-Match must carry the link's ETag, or \"*\".",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Partially update a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the patch is based on",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch, e.g. {\\",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ShortURL"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated link"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid fields, in data.errors",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationErrors"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/{shortURL}/history": {
+            "get": {
+                "description": "Lists every recorded change to a short URL, newest first: its version, what was done, who did it (from the X-Actor header) and from where, and the old and new value of each field that changed. Passwords are never shown; a change is reported as password, with whether the link was protected before and after.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Get the history of a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LinkVersion"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/{shortURL}/qr": {
+            "get": {
+                "description": "Renders a QR code that opens the short URL. The encoded URL carries ?qr=1, so scans are reported separately as qr_scans in the link's stats.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "QR code for a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "svg"
+                        ],
+                        "type": "string",
+                        "default": "png",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Width and height in pixels, 64-2048",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "L",
+                            "M",
+                            "Q",
+                            "H"
+                        ],
+                        "type": "string",
+                        "description": "Error correction level; defaults to H with a logo, M otherwise",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 4,
+                        "description": "Quiet zone in modules, 0-16",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "000000",
+                        "description": "Foreground color as RRGGBB or RRGGBBAA hex",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ffffff",
+                        "description": "Background color as RRGGBB or RRGGBBAA hex",
+                        "name": "bg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL of a PNG, JPEG or GIF image drawn in the center",
+                        "name": "logo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/{shortURL}/restore": {
+            "post": {
+                "description": "Takes a short URL out of the trash, so it redirects again with its settings, tags and click history intact. Its destinations are checked against the current domain policy and threat lists first; a link the domain policy disabled is restored disabled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Restore a deleted short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/{shortURL}/rollback": {
+            "post": {
+                "description": "Restores the configuration a short URL had at a version of its history: destination, password, rules, schedule, tags, folder, notes and the other editable settings. Access counts and clicks are kept. The rollback is recorded as a new version. Destinations are checked against the current domain policy and threat lists, and a folder deleted since leaves the link unfiled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Roll a short URL back to an earlier version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the rollback is based on; 412 if the link has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Version to roll back to",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RollbackPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ShortURL"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/{shortURL}/stats": {
+            "get": {
+                "description": "Fetches the statistics (e.g., access count, QR code scans, clicks per country and per split variant) for a given short URL",
+                "tags": [
+                    "shortlinks"
+                ],
+                "summary": "Get short URL statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "shortURL",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statistics fetched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.ShortURLStats"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the link, for If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Short URL is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to fetch statistics",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.BulkActionRequest": {
+            "type": "object",
+            "required": [
+                "action"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "delete",
+                        "disable",
+                        "enable",
+                        "repoint",
+                        "retag",
+                        "move"
+                    ]
+                },
+                "created_after": {
+                    "type": "string"
+                },
+                "created_before": {
+                    "type": "string"
+                },
+                "folder_id": {
+                    "type": "integer"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "short_urls": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tag": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to_folder_id": {
+                    "type": "integer"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BulkActionResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "affected": {
+                    "type": "integer"
+                },
+                "short_urls": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.BulkCreateResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BulkCreateResult"
+                    }
+                }
+            }
+        },
+        "models.BulkCreateResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "link": {
+                    "$ref": "#/definitions/models.ShortURL"
+                },
+                "row": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CampaignStats": {
+            "type": "object",
+            "properties": {
+                "access_count": {
+                    "type": "integer"
+                },
+                "links": {
+                    "type": "integer"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CountryClicks": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "country": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DomainPolicy": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "match_type": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "pattern": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DomainPolicyPayload": {
+            "type": "object",
+            "required": [
+                "action",
+                "match_type",
+                "pattern"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "match_type": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "pattern": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldChange": {
+            "type": "object",
+            "properties": {
+                "new": {},
+                "old": {}
+            }
+        },
+        "models.Folder": {
+            "type": "object",
+            "properties": {
+                "access_count": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "links": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FolderPayload": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LinkVersion": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.FieldChange"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "rolled_back_to": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OpenGraph": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Passthrough": {
+            "type": "object",
+            "properties": {
+                "path": {
+                    "description": "Path appends segments after the slug to the destination path, so\n/docs/getting-started goes to \u003cdestination\u003e/getting-started.",
+                    "type": "boolean"
+                },
+                "query": {
+                    "description": "Query merges the incoming query parameters into the destination.",
+                    "type": "boolean"
+                },
+                "query_conflict": {
+                    "description": "QueryConflict decides what happens when a parameter is present in both:\nkeep the destination's value (default), use the request's, or keep both.",
+                    "type": "string",
+                    "enum": [
+                        "destination",
+                        "request",
+                        "append"
+                    ]
+                }
+            }
+        },
+        "models.RedirectRule": {
+            "type": "object",
+            "properties": {
+                "condition": {
+                    "type": "string",
+                    "example": "country == 'DE' \u0026\u0026 hour \u003e= 18"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Data is omitted if nil or empty"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "statusCode": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RollbackPayload": {
+            "type": "object",
+            "required": [
+                "version"
+            ],
+            "properties": {
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Rotation": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "description": "Mode is round_robin (default), which takes the URLs in turn across\nall instances, or random.",
+                    "type": "string",
+                    "enum": [
+                        "round_robin",
+                        "random"
+                    ]
+                },
+                "urls": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.SearchResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SearchResult"
+                    }
+                }
+            }
+        },
+        "models.SearchResult": {
+            "type": "object",
+            "properties": {
+                "access_count": {
+                    "type": "integer"
+                },
+                "activate_at": {
+                    "description": "ActivateAt and DeactivateAt bound when the link redirects. Outside the\nwindow visitors are sent to InactiveURL, or get 404 (not yet active)\nor 410 (expired) when it is empty.",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deactivate_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the link is in the trash, and PurgeAt is when\nit will be removed for good. Both are only shown in the trash listing.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "disabled": {
+                    "type": "boolean"
+                },
+                "disabled_reason": {
+                    "type": "string"
+                },
+                "folder_id": {
+                    "description": "FolderID files the link in a folder. On update, 0 removes it from its\nfolder.",
+                    "type": "integer"
+                },
+                "highlights": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image_url": {
+                    "type": "string"
+                },
+                "inactive_url": {
+                    "type": "string"
+                },
+                "interstitial": {
+                    "description": "Interstitial shows a page with the destination before every redirect.\nOn update, omitting it keeps the current setting.",
+                    "type": "boolean"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks limits how many redirects the link serves; nil means\nunlimited. On update, 0 removes the limit.",
+                    "type": "integer"
+                },
+                "metadata_fetched_at": {
+                    "type": "string"
+                },
+                "notes": {
+                    "description": "Notes is free text for the link's owners, included in search. On\nupdate, omitting it keeps the current notes and \"\" clears them.",
+                    "type": "string"
+                },
+                "open_graph": {
+                    "description": "OpenGraph overrides the card link preview crawlers show for the link.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.OpenGraph"
+                        }
+                    ]
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "passthrough": {
+                    "description": "Passthrough controls whether the visitor's query string and any path\nafter the slug are carried over to the destination.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Passthrough"
+                        }
+                    ]
+                },
+                "password": {
+                    "description": "Password is write-only: set it on create/update to protect the link,\nor send an empty string on update to remove protection.",
+                    "type": "string"
+                },
+                "password_protected": {
+                    "type": "boolean"
+                },
+                "purge_at": {
+                    "type": "string"
+                },
+                "rank": {
+                    "type": "number"
+                },
+                "redirect_type": {
+                    "description": "RedirectType is the HTTP status used for the redirect: 301, 302\n(default), 307 or 308.",
+                    "type": "integer"
+                },
+                "rotation": {
+                    "description": "Rotation cycles visitors that no targeting rule matches through a\nlist of destinations. It cannot be combined with Split.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Rotation"
+                        }
+                    ]
+                },
+                "rules": {
+                    "description": "Rules send visitors to a different destination when a CEL condition\nover the request holds. They are checked in order, before Targeting.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RedirectRule"
+                    }
+                },
+                "short_url": {
+                    "type": "string"
+                },
+                "split": {
+                    "description": "Split distributes visitors that no targeting rule matches across\nweighted variants instead of sending them to OriginalURL.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Split"
+                        }
+                    ]
+                },
+                "tags": {
+                    "description": "Tags label the link for grouping and filtering. They are lower-case\nand created on first use. On update, omitting tags keeps them and an\nempty list removes them.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "targeting": {
+                    "description": "Targeting sends visitors to a different destination based on their\nuser agent or location. Rules are checked in order and OriginalURL is\nthe default.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TargetingRule"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_content": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                },
+                "utm_term": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ShortURL": {
+            "type": "object",
+            "properties": {
                 "access_count": {
                     "type": "integer"
                 },
+                "activate_at": {
+                    "description": "ActivateAt and DeactivateAt bound when the link redirects. Outside the\nwindow visitors are sent to InactiveURL, or get 404 (not yet active)\nor 410 (expired) when it is empty.",
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "deactivate_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the link is in the trash, and PurgeAt is when\nit will be removed for good. Both are only shown in the trash listing.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "disabled": {
+                    "type": "boolean"
+                },
+                "disabled_reason": {
+                    "type": "string"
+                },
+                "folder_id": {
+                    "description": "FolderID files the link in a folder. On update, 0 removes it from its\nfolder.",
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
                 },
+                "image_url": {
+                    "type": "string"
+                },
+                "inactive_url": {
+                    "type": "string"
+                },
+                "interstitial": {
+                    "description": "Interstitial shows a page with the destination before every redirect.\nOn update, omitting it keeps the current setting.",
+                    "type": "boolean"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks limits how many redirects the link serves; nil means\nunlimited. On update, 0 removes the limit.",
+                    "type": "integer"
+                },
+                "metadata_fetched_at": {
+                    "type": "string"
+                },
+                "notes": {
+                    "description": "Notes is free text for the link's owners, included in search. On\nupdate, omitting it keeps the current notes and \"\" clears them.",
+                    "type": "string"
+                },
+                "open_graph": {
+                    "description": "OpenGraph overrides the card link preview crawlers show for the link.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.OpenGraph"
+                        }
+                    ]
+                },
                 "original_url": {
                     "type": "string"
                 },
+                "passthrough": {
+                    "description": "Passthrough controls whether the visitor's query string and any path\nafter the slug are carried over to the destination.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Passthrough"
+                        }
+                    ]
+                },
+                "password": {
+                    "description": "Password is write-only: set it on create/update to protect the link,\nor send an empty string on update to remove protection.",
+                    "type": "string"
+                },
+                "password_protected": {
+                    "type": "boolean"
+                },
+                "purge_at": {
+                    "type": "string"
+                },
+                "redirect_type": {
+                    "description": "RedirectType is the HTTP status used for the redirect: 301, 302\n(default), 307 or 308.",
+                    "type": "integer"
+                },
+                "rotation": {
+                    "description": "Rotation cycles visitors that no targeting rule matches through a\nlist of destinations. It cannot be combined with Split.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Rotation"
+                        }
+                    ]
+                },
+                "rules": {
+                    "description": "Rules send visitors to a different destination when a CEL condition\nover the request holds. They are checked in order, before Targeting.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RedirectRule"
+                    }
+                },
                 "short_url": {
                     "type": "string"
                 },
+                "split": {
+                    "description": "Split distributes visitors that no targeting rule matches across\nweighted variants instead of sending them to OriginalURL.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Split"
+                        }
+                    ]
+                },
+                "tags": {
+                    "description": "Tags label the link for grouping and filtering. They are lower-case\nand created on first use. On update, omitting tags keeps them and an\nempty list removes them.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "targeting": {
+                    "description": "Targeting sends visitors to a different destination based on their\nuser agent or location. Rules are checked in order and OriginalURL is\nthe default.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TargetingRule"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_content": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                },
+                "utm_term": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
@@ -314,8 +2200,398 @@ const docTemplate = `{
                 "original_url"
             ],
             "properties": {
+                "activate_at": {
+                    "type": "string"
+                },
+                "deactivate_at": {
+                    "type": "string"
+                },
+                "folder_id": {
+                    "type": "integer"
+                },
+                "inactive_url": {
+                    "type": "string"
+                },
+                "interstitial": {
+                    "type": "boolean"
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "open_graph": {
+                    "$ref": "#/definitions/models.OpenGraph"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "passthrough": {
+                    "$ref": "#/definitions/models.Passthrough"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "redirect_type": {
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ]
+                },
+                "rotation": {
+                    "$ref": "#/definitions/models.Rotation"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RedirectRule"
+                    }
+                },
+                "split": {
+                    "$ref": "#/definitions/models.Split"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "targeting": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TargetingRule"
+                    }
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_content": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                },
+                "utm_term": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ShortURLStats": {
+            "type": "object",
+            "properties": {
+                "access_count": {
+                    "type": "integer"
+                },
+                "activate_at": {
+                    "description": "ActivateAt and DeactivateAt bound when the link redirects. Outside the\nwindow visitors are sent to InactiveURL, or get 404 (not yet active)\nor 410 (expired) when it is empty.",
+                    "type": "string"
+                },
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CountryClicks"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deactivate_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the link is in the trash, and PurgeAt is when\nit will be removed for good. Both are only shown in the trash listing.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "disabled": {
+                    "type": "boolean"
+                },
+                "disabled_reason": {
+                    "type": "string"
+                },
+                "folder_id": {
+                    "description": "FolderID files the link in a folder. On update, 0 removes it from its\nfolder.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image_url": {
+                    "type": "string"
+                },
+                "inactive_url": {
+                    "type": "string"
+                },
+                "interstitial": {
+                    "description": "Interstitial shows a page with the destination before every redirect.\nOn update, omitting it keeps the current setting.",
+                    "type": "boolean"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks limits how many redirects the link serves; nil means\nunlimited. On update, 0 removes the limit.",
+                    "type": "integer"
+                },
+                "metadata_fetched_at": {
+                    "type": "string"
+                },
+                "notes": {
+                    "description": "Notes is free text for the link's owners, included in search. On\nupdate, omitting it keeps the current notes and \"\" clears them.",
+                    "type": "string"
+                },
+                "open_graph": {
+                    "description": "OpenGraph overrides the card link preview crawlers show for the link.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.OpenGraph"
+                        }
+                    ]
+                },
                 "original_url": {
                     "type": "string"
+                },
+                "passthrough": {
+                    "description": "Passthrough controls whether the visitor's query string and any path\nafter the slug are carried over to the destination.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Passthrough"
+                        }
+                    ]
+                },
+                "password": {
+                    "description": "Password is write-only: set it on create/update to protect the link,\nor send an empty string on update to remove protection.",
+                    "type": "string"
+                },
+                "password_protected": {
+                    "type": "boolean"
+                },
+                "purge_at": {
+                    "type": "string"
+                },
+                "qr_scans": {
+                    "type": "integer"
+                },
+                "redirect_type": {
+                    "description": "RedirectType is the HTTP status used for the redirect: 301, 302\n(default), 307 or 308.",
+                    "type": "integer"
+                },
+                "rotation": {
+                    "description": "Rotation cycles visitors that no targeting rule matches through a\nlist of destinations. It cannot be combined with Split.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Rotation"
+                        }
+                    ]
+                },
+                "rules": {
+                    "description": "Rules send visitors to a different destination when a CEL condition\nover the request holds. They are checked in order, before Targeting.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RedirectRule"
+                    }
+                },
+                "short_url": {
+                    "type": "string"
+                },
+                "split": {
+                    "description": "Split distributes visitors that no targeting rule matches across\nweighted variants instead of sending them to OriginalURL.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Split"
+                        }
+                    ]
+                },
+                "tags": {
+                    "description": "Tags label the link for grouping and filtering. They are lower-case\nand created on first use. On update, omitting tags keeps them and an\nempty list removes them.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "targeting": {
+                    "description": "Targeting sends visitors to a different destination based on their\nuser agent or location. Rules are checked in order and OriginalURL is\nthe default.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TargetingRule"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_content": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                },
+                "utm_term": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VariantClicks"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Split": {
+            "type": "object",
+            "properties": {
+                "sticky": {
+                    "description": "Sticky keeps returning visitors on the variant they were first\nassigned, using a cookie.",
+                    "type": "boolean"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SplitVariant"
+                    }
+                }
+            }
+        },
+        "models.SplitVariant": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "b"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 50
+                }
+            }
+        },
+        "models.Tag": {
+            "type": "object",
+            "properties": {
+                "access_count": {
+                    "type": "integer"
+                },
+                "links": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TagPayload": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TargetingRule": {
+            "type": "object",
+            "properties": {
+                "browser": {
+                    "type": "string",
+                    "enum": [
+                        "chrome",
+                        "safari",
+                        "firefox",
+                        "edge",
+                        "opera",
+                        "samsung",
+                        "other"
+                    ]
+                },
+                "continent": {
+                    "type": "string",
+                    "enum": [
+                        "AF",
+                        "AN",
+                        "AS",
+                        "EU",
+                        "NA",
+                        "OC",
+                        "SA"
+                    ]
+                },
+                "country": {
+                    "type": "string",
+                    "example": "DE"
+                },
+                "device": {
+                    "type": "string",
+                    "enum": [
+                        "mobile",
+                        "tablet",
+                        "desktop",
+                        "bot"
+                    ]
+                },
+                "os": {
+                    "type": "string",
+                    "enum": [
+                        "ios",
+                        "android",
+                        "windows",
+                        "macos",
+                        "linux",
+                        "chromeos",
+                        "other"
+                    ]
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ValidationErrors": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.VariantClicks": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "variant": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
                 }
             }
         }
@@ -77,6 +77,41 @@ func (r *RedisCache) Delete(key string) error {
 	return nil
 }
 
+// DeleteKeys deletes many keys, in chunks so a single command stays small.
+func (r *RedisCache) DeleteKeys(keys []string) error {
+	ctx := context.Background()
+	const chunkSize = 1000
+	for start := 0; start < len(keys); start += chunkSize {
+		chunk := keys[start:min(start+chunkSize, len(keys))]
+		if err := r.Client.Del(ctx, chunk...).Err(); err != nil {
+			log.Error().
+				Err(err).
+				Int("keys", len(keys)).
+				Msg("Error deleting keys from Redis")
+			return err
+		}
+	}
+	log.Info().
+		Int("keys", len(keys)).
+		Msg("Cache keys deleted successfully")
+	return nil
+}
+
+// Incr atomically increments the counter at key and returns the new value.
+// Missing keys start at zero.
+func (r *RedisCache) Incr(key string) (int64, error) {
+	ctx := context.Background()
+	val, err := r.Client.Incr(ctx, key).Result()
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("key", key).
+			Msg("Error incrementing key in Redis")
+		return 0, err
+	}
+	return val, nil
+}
+
 func (r *RedisCache) CacheStats(key string, value string, expiration time.Duration) error {
 	ctx := context.Background()
 	err := r.Client.Set(ctx, key, value, expiration).Err()
@@ -2,7 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -14,6 +17,39 @@ type Config struct {
 	DBPassword string
 	DBAddress  string
 	DBName     string
+
+	// ThreatListPaths are local threat list files (Safe Browsing v4 update
+	// JSON or plain domain/URL lists) that destinations are screened against.
+	ThreatListPaths           []string
+	ThreatListRefreshInterval time.Duration
+
+	// LinkCookieSecret signs the cookies issued after a visitor unlocks a
+	// password-protected link. A random secret is used when it is empty.
+	LinkCookieSecret string
+
+	// GeoIPDBPath is a MaxMind-format .mmdb file (e.g. GeoLite2-Country)
+	// used for geo-targeting and click analytics. Geo lookups are skipped
+	// when it is empty.
+	GeoIPDBPath string
+
+	// TrustedProxies are the proxy IPs or CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers are used to find the client IP. When it is empty
+	// the connection's remote address is used.
+	TrustedProxies []string
+	// TrustedPlatformHeader names a header set by the hosting platform that
+	// carries the client IP, such as CF-Connecting-IP.
+	TrustedPlatformHeader string
+
+	// TrashRetention is how long deleted links stay in the trash, where they
+	// can be restored and their slugs are not reissued, before they are
+	// purged. TrashPurgeInterval is how often the purge runs.
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// PublicBaseURL is the scheme and host short links are served from,
+	// such as https://kort.link, used for the URL encoded in QR codes.
+	// When it is empty the request's Host header is used.
+	PublicBaseURL string
 }
 
 var Envs = InitializeConfig()
@@ -31,6 +67,20 @@ func InitializeConfig() Config {
 		DBPassword: getEnv("DB_PASSWORD", "pass_3"),
 		DBName:     getEnv("DB_NAME", "kortlink"),
 		DBAddress:  fmt.Sprintf("%s:%s", getEnv("DB_HOST", "127.0.0.1"), getEnv("DB_PORT", "5432")),
+
+		ThreatListPaths:           getEnvList("THREAT_LIST_PATHS"),
+		ThreatListRefreshInterval: getEnvDuration("THREAT_LIST_REFRESH_INTERVAL", time.Hour),
+
+		LinkCookieSecret: getEnv("LINK_COOKIE_SECRET", ""),
+
+		GeoIPDBPath:           getEnv("GEOIP_DB_PATH", ""),
+		TrustedProxies:        getEnvList("TRUSTED_PROXIES"),
+		TrustedPlatformHeader: getEnv("TRUSTED_PLATFORM_HEADER", ""),
+
+		TrashRetention:     getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour),
+
+		PublicBaseURL: getEnvBaseURL("PUBLIC_BASE_URL"),
 	}
 }
 
@@ -40,3 +90,41 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Invalid duration, using default")
+		return fallback
+	}
+	return d
+}
+
+// getEnvBaseURL reads an absolute http or https URL without a trailing
+// slash. Invalid values are logged and ignored.
+func getEnvBaseURL(key string) string {
+	value := strings.TrimSuffix(strings.TrimSpace(getEnv(key, "")), "/")
+	if value == "" {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		log.Error().Str("key", key).Str("value", value).Msg("Invalid base URL, ignoring")
+		return ""
+	}
+	return value
+}
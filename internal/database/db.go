@@ -36,6 +36,47 @@ func (s *PostgresStorage) InitializeDatabase() error {
 	}
 	log.Info().Msg("url table created successfully")
 
+	if err := s.migrateUrlsTable(); err != nil {
+		log.Error().Err(err).Msg("Failed to migrate urls table")
+		return err
+	}
+
+	if err := s.createDomainPoliciesTable(); err != nil {
+		log.Error().Err(err).Msg("Failed to create domain_policies table")
+		return err
+	}
+	log.Info().Msg("domain_policies table created successfully")
+
+	if err := s.createClicksTable(); err != nil {
+		log.Error().Err(err).Msg("Failed to create clicks table")
+		return err
+	}
+	log.Info().Msg("clicks table created successfully")
+
+	if err := s.createFoldersTable(); err != nil {
+		log.Error().Err(err).Msg("Failed to create folders table")
+		return err
+	}
+	log.Info().Msg("folders table created successfully")
+
+	if err := s.createTagsTables(); err != nil {
+		log.Error().Err(err).Msg("Failed to create tags tables")
+		return err
+	}
+	log.Info().Msg("tags tables created successfully")
+
+	if err := s.createSearchIndexes(); err != nil {
+		log.Error().Err(err).Msg("Failed to create search indexes")
+		return err
+	}
+	log.Info().Msg("search indexes created successfully")
+
+	if err := s.createLinkVersionsTable(); err != nil {
+		log.Error().Err(err).Msg("Failed to create link_versions table")
+		return err
+	}
+	log.Info().Msg("link_versions table created successfully")
+
 	return nil
 }
 
@@ -53,3 +94,164 @@ func (s *PostgresStorage) createUrlsTable() error {
 	_, err := s.pool.Exec(context.Background(), sql)
 	return err
 }
+
+// migrateUrlsTable adds columns introduced after the urls table was first
+// created, so existing deployments pick them up on start.
+func (s *PostgresStorage) migrateUrlsTable() error {
+	sql := `
+	ALTER TABLE urls
+		ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE,
+		ADD COLUMN IF NOT EXISTS disabled_reason TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS max_clicks INT,
+		ADD COLUMN IF NOT EXISTS activate_at TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS deactivate_at TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS inactive_url TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS redirect_type INT NOT NULL DEFAULT 302,
+		ADD COLUMN IF NOT EXISTS passthrough JSONB,
+		ADD COLUMN IF NOT EXISTS utm_source TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS utm_medium TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS utm_campaign TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS utm_term TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS utm_content TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS targeting JSONB,
+		ADD COLUMN IF NOT EXISTS split JSONB,
+		ADD COLUMN IF NOT EXISTS rules JSONB,
+		ADD COLUMN IF NOT EXISTS rotation JSONB,
+		ADD COLUMN IF NOT EXISTS interstitial BOOLEAN NOT NULL DEFAULT FALSE,
+		ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS image_url TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS metadata_fetched_at TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS open_graph JSONB,
+		ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+	CREATE INDEX IF NOT EXISTS urls_utm_campaign_idx ON urls (utm_campaign);
+	CREATE INDEX IF NOT EXISTS urls_deleted_at_idx ON urls (deleted_at) WHERE deleted_at IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS urls_metadata_pending_idx ON urls (created_at, id) WHERE metadata_fetched_at IS NULL AND deleted_at IS NULL;
+	`
+	_, err := s.pool.Exec(context.Background(), sql)
+	return err
+}
+
+func (s *PostgresStorage) createDomainPoliciesTable() error {
+	sql := `
+    CREATE TABLE IF NOT EXISTS domain_policies (
+		id SERIAL PRIMARY KEY,
+		pattern TEXT NOT NULL,
+		match_type TEXT NOT NULL,
+		action TEXT NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (pattern, match_type, action)
+	);
+    `
+	_, err := s.pool.Exec(context.Background(), sql)
+	return err
+}
+
+// createClicksTable stores one row per redirect for analytics. Columns added
+// after the table was introduced are migrated in place.
+func (s *PostgresStorage) createClicksTable() error {
+	sql := `
+    CREATE TABLE IF NOT EXISTS clicks (
+		id BIGSERIAL PRIMARY KEY,
+		url_id INT NOT NULL REFERENCES urls (id) ON DELETE CASCADE,
+		country TEXT NOT NULL DEFAULT '',
+		clicked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	ALTER TABLE clicks
+		ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS clicks_url_id_idx ON clicks (url_id, clicked_at);
+    `
+	_, err := s.pool.Exec(context.Background(), sql)
+	return err
+}
+
+// createFoldersTable stores the folders links can be filed in. A link is in
+// at most one folder; deleting the folder leaves its links unfiled.
+func (s *PostgresStorage) createFoldersTable() error {
+	sql := `
+    CREATE TABLE IF NOT EXISTS folders (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	ALTER TABLE urls
+		ADD COLUMN IF NOT EXISTS folder_id INT REFERENCES folders (id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS urls_folder_id_idx ON urls (folder_id);
+    `
+	_, err := s.pool.Exec(context.Background(), sql)
+	return err
+}
+
+// createTagsTables stores tag names and the many-to-many link between tags
+// and urls.
+func (s *PostgresStorage) createTagsTables() error {
+	sql := `
+    CREATE TABLE IF NOT EXISTS tags (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS url_tags (
+		url_id INT NOT NULL REFERENCES urls (id) ON DELETE CASCADE,
+		tag_id INT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+		PRIMARY KEY (url_id, tag_id)
+	);
+	CREATE INDEX IF NOT EXISTS url_tags_tag_id_idx ON url_tags (tag_id);
+    `
+	_, err := s.pool.Exec(context.Background(), sql)
+	return err
+}
+
+// createSearchIndexes backs link search: a weighted tsvector for word
+// matches and trigram indexes for fragments of URLs, titles, notes and tags.
+// The 'simple' configuration is used because links are in many languages
+// and URLs should not be stemmed.
+func (s *PostgresStorage) createSearchIndexes() error {
+	sql := `
+	CREATE EXTENSION IF NOT EXISTS pg_trgm;
+	ALTER TABLE urls
+		ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', title), 'A') ||
+			setweight(to_tsvector('simple', short_url), 'A') ||
+			setweight(to_tsvector('simple', notes), 'B') ||
+			setweight(to_tsvector('simple', original_url), 'C')
+		) STORED;
+	CREATE INDEX IF NOT EXISTS urls_search_vector_idx ON urls USING GIN (search_vector);
+	CREATE INDEX IF NOT EXISTS urls_original_url_trgm_idx ON urls USING GIN (original_url gin_trgm_ops);
+	CREATE INDEX IF NOT EXISTS urls_short_url_trgm_idx ON urls USING GIN (short_url gin_trgm_ops);
+	CREATE INDEX IF NOT EXISTS urls_title_trgm_idx ON urls USING GIN (title gin_trgm_ops);
+	CREATE INDEX IF NOT EXISTS urls_notes_trgm_idx ON urls USING GIN (notes gin_trgm_ops);
+	CREATE INDEX IF NOT EXISTS tags_name_trgm_idx ON tags USING GIN (name gin_trgm_ops);
+	`
+	_, err := s.pool.Exec(context.Background(), sql)
+	return err
+}
+
+// createLinkVersionsTable stores the history of each link: who changed what,
+// and the link's configuration after the change, which rollbacks restore.
+// urls.version is the link's latest version; versions are numbered by
+// incrementing it, which also serializes concurrent changes to a link.
+func (s *PostgresStorage) createLinkVersionsTable() error {
+	sql := `
+	ALTER TABLE urls
+		ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 0;
+	CREATE TABLE IF NOT EXISTS link_versions (
+		url_id INT NOT NULL REFERENCES urls (id) ON DELETE CASCADE,
+		version INT NOT NULL,
+		action TEXT NOT NULL,
+		actor TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		changes JSONB NOT NULL DEFAULT '{}',
+		state JSONB NOT NULL,
+		rolled_back_to INT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (url_id, version)
+	);
+	`
+	_, err := s.pool.Exec(context.Background(), sql)
+	return err
+}
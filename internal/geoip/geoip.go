@@ -0,0 +1,94 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Continents are the continent codes used in MaxMind databases.
+var Continents = []string{"AF", "AN", "AS", "EU", "NA", "OC", "SA"}
+
+// Location is the part of a GeoIP record used for targeting and analytics.
+// Country is an ISO 3166-1 alpha-2 code and Continent one of Continents;
+// both are empty when the address is not in the database.
+type Location struct {
+	Country   string `json:"country"`
+	Continent string `json:"continent"`
+}
+
+// record mirrors the fields read from GeoLite2/GeoIP2 Country and City
+// databases. RegisteredCountry is used for addresses without a Country,
+// such as those of some mobile carriers and anycast networks.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+}
+
+// Resolver looks up client IPs in a MaxMind-format .mmdb database on disk.
+type Resolver struct {
+	path string
+
+	mu     sync.RWMutex
+	reader *maxminddb.Reader
+}
+
+func NewResolver(path string) *Resolver {
+	return &Resolver{path: path}
+}
+
+// Enabled reports whether a database file is configured.
+func (r *Resolver) Enabled() bool {
+	return r.path != ""
+}
+
+// Load opens the database and replaces the active one. On error the
+// previously loaded database stays in place.
+func (r *Resolver) Load() error {
+	reader, err := maxminddb.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("could not open GeoIP database %s: %w", r.path, err)
+	}
+
+	r.mu.Lock()
+	previous := r.reader
+	r.reader = reader
+	r.mu.Unlock()
+
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+// Lookup resolves ip to a location. It reports false when no database is
+// loaded or the address is not in it.
+func (r *Resolver) Lookup(ip net.IP) (Location, bool) {
+	if ip == nil {
+		return Location{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.reader == nil {
+		return Location{}, false
+	}
+
+	var rec record
+	if err := r.reader.Lookup(ip, &rec); err != nil {
+		return Location{}, false
+	}
+	location := Location{Country: rec.Country.ISOCode, Continent: rec.Continent.Code}
+	if location.Country == "" {
+		location.Country = rec.RegisteredCountry.ISOCode
+	}
+	return location, location.Country != "" || location.Continent != ""
+}
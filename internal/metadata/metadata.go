@@ -0,0 +1,315 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// UserAgent identifies the fetcher to destination sites and is the name
+// matched against robots.txt groups.
+const UserAgent = "KortlinkBot/1.0 (+https://github.com/gboliknow/Kortlink)"
+
+const (
+	robotsName = "kortlinkbot"
+
+	fetchTimeout    = 10 * time.Second
+	dialTimeout     = 5 * time.Second
+	maxRedirects    = 5
+	maxPageBytes    = 1 << 20
+	maxRobotsBytes  = 512 << 10
+	maxHeaderBytes  = 64 << 10
+	maxTitleLength  = 300
+	maxDescLength   = 1000
+	maxImageURLSize = 2048
+	maxImageBytes   = 1 << 20
+	maxImagePixels  = 2048 * 2048
+)
+
+var (
+	ErrBlockedAddress     = errors.New("destination resolves to a non-public address")
+	ErrDisallowedByRobots = errors.New("destination disallows fetching in robots.txt")
+	ErrNotHTML            = errors.New("destination is not an HTML page")
+	ErrImageTooLarge      = errors.New("image is too large")
+)
+
+// Metadata is what is read from the destination page. Image is an absolute
+// URL.
+type Metadata struct {
+	Title       string
+	Description string
+	Image       string
+}
+
+// blockedPrefixes are ranges that are not covered by the net.IP helpers
+// but are not reachable on the public internet either.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetcher reads page metadata over HTTP. Connections to loopback, private,
+// link-local and other non-public addresses are refused when dialing, after
+// DNS resolution, so redirects and rebinding cannot reach internal services.
+type Fetcher struct {
+	client *http.Client
+}
+
+func NewFetcher() *Fetcher {
+	return newFetcher(func(addrPort netip.AddrPort) bool {
+		return isPublicAddr(addrPort.Addr())
+	})
+}
+
+// newFetcher returns a Fetcher that only connects to addresses that allowed
+// accepts. It is checked for every connection, including those made to
+// follow redirects.
+func newFetcher(allowed func(netip.AddrPort) bool) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !allowed(addrPort) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    dialTimeout,
+		ResponseHeaderTimeout:  fetchTimeout,
+		MaxResponseHeaderBytes: maxHeaderBytes,
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   fetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkScheme(req.URL)
+		},
+	}
+	return &Fetcher{client: client}
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// Fetch reads the title, description and Open Graph image of rawURL. It
+// honours the site's robots.txt and only reads the first part of the page.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+	allowed, err := f.robotsAllowed(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrDisallowedByRobots
+	}
+
+	resp, err := f.get(ctx, u.String(), "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("destination returned %s", resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxPageBytes), contentType)
+	if err != nil {
+		return nil, err
+	}
+	return parse(body, resp.Request.URL), nil
+}
+
+// FetchImage downloads and decodes a PNG, JPEG or GIF image, with the same
+// address restrictions as Fetch. Images over 1 MB or 2048x2048 pixels are
+// rejected before decoding.
+func (f *Fetcher) FetchImage(ctx context.Context, rawURL string) (image.Image, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+	resp, err := f.get(ctx, u.String(), "image/png,image/jpeg,image/gif")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, ErrImageTooLarge
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+func (f *Fetcher) get(ctx context.Context, rawURL, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", accept)
+	return f.client.Do(req)
+}
+
+// parse reads metadata from the document head. og: properties win over the
+// plain title and description; the tokenizer stops at <body>.
+func parse(r io.Reader, base *url.URL) *Metadata {
+	var title, description, ogTitle, ogDescription, ogImage string
+	z := html.NewTokenizer(r)
+	inTitle := false
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				break loop
+			case "title":
+				inTitle = title == ""
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+				attrs := make(map[string]string)
+				for {
+					key, val, more := z.TagAttr()
+					attrs[string(key)] = string(val)
+					if !more {
+						break
+					}
+				}
+				key := attrs["property"]
+				if key == "" {
+					key = attrs["name"]
+				}
+				content := attrs["content"]
+				switch strings.ToLower(key) {
+				case "description":
+					description = content
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "og:image", "og:image:url", "og:image:secure_url":
+					if ogImage == "" {
+						ogImage = content
+					}
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break loop
+			}
+		}
+	}
+
+	meta := &Metadata{
+		Title:       clean(firstNonEmpty(ogTitle, title), maxTitleLength),
+		Description: clean(firstNonEmpty(ogDescription, description), maxDescLength),
+	}
+	if ogImage != "" {
+		if image, err := base.Parse(strings.TrimSpace(ogImage)); err == nil && checkScheme(image) == nil && len(image.String()) <= maxImageURLSize {
+			meta.Image = image.String()
+		}
+	}
+	return meta
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// clean collapses whitespace and truncates to maxRunes characters.
+func clean(s string, maxRunes int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxRunes {
+		s = string(runes[:maxRunes])
+	}
+	return s
+}
@@ -0,0 +1,347 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// allowServers returns an address check that only lets the fetcher reach
+// the given test servers.
+func allowServers(t *testing.T, servers ...*httptest.Server) func(netip.AddrPort) bool {
+	t.Helper()
+	allowed := make(map[netip.AddrPort]bool)
+	for _, server := range servers {
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrPort, err := netip.ParseAddrPort(u.Host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		allowed[addrPort] = true
+	}
+	return func(addrPort netip.AddrPort) bool {
+		return allowed[addrPort]
+	}
+}
+
+// newSite serves robots and page at /robots.txt and every other path.
+func newSite(t *testing.T, robots func(http.ResponseWriter), page http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			robots(w)
+			return
+		}
+		page(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func noRobots(w http.ResponseWriter) {
+	http.NotFound(w, nil)
+}
+
+func htmlPage(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"0.0.0.0", false},
+		{"100.64.0.1", false},
+		{"198.18.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestFetchBlocksLoopback(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher().Fetch(context.Background(), server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("Fetch of a loopback server returned %v, want ErrBlockedAddress", err)
+	}
+	if _, err := NewFetcher().FetchImage(context.Background(), server.URL+"/image.png"); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("FetchImage of a loopback server returned %v, want ErrBlockedAddress", err)
+	}
+	if hits != 0 {
+		t.Fatalf("blocked server received %d requests", hits)
+	}
+}
+
+func TestFetchRechecksRedirects(t *testing.T) {
+	internal := newSite(t, noRobots, htmlPage("<title>Internal</title>"))
+	public := newSite(t, noRobots, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/admin", http.StatusFound)
+	})
+
+	f := newFetcher(allowServers(t, public))
+	_, err := f.Fetch(context.Background(), public.URL+"/page")
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("Fetch following a redirect to a blocked address returned %v, want ErrBlockedAddress", err)
+	}
+
+	f = newFetcher(allowServers(t, public, internal))
+	meta, err := f.Fetch(context.Background(), public.URL+"/page")
+	if err != nil {
+		t.Fatalf("Fetch following an allowed redirect: %v", err)
+	}
+	if meta.Title != "Internal" {
+		t.Fatalf("Title = %q, want %q", meta.Title, "Internal")
+	}
+}
+
+func TestFetchStopsAfterMaxRedirects(t *testing.T) {
+	var server *httptest.Server
+	server = newSite(t, noRobots, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+r.URL.Path+"x", http.StatusFound)
+	})
+	if _, err := newFetcher(allowServers(t, server)).Fetch(context.Background(), server.URL+"/"); err == nil {
+		t.Fatal("Fetch followed an endless redirect chain")
+	}
+}
+
+func TestFetchRejectsUnsupportedSchemes(t *testing.T) {
+	for _, rawURL := range []string{"ftp://example.com/", "file:///etc/passwd", "javascript:alert(1)"} {
+		if _, err := NewFetcher().Fetch(context.Background(), rawURL); err == nil {
+			t.Errorf("Fetch(%q) succeeded", rawURL)
+		}
+	}
+}
+
+func TestFetchRobots(t *testing.T) {
+	tests := []struct {
+		name    string
+		robots  func(http.ResponseWriter)
+		path    string
+		allowed bool
+	}{
+		{"missing", noRobots, "/page", true},
+		{"unreachable", func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, "/page", false},
+		{"disallowed for everyone", func(w http.ResponseWriter) {
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /"))
+		}, "/page", false},
+		{"disallowed for the bot", func(w http.ResponseWriter) {
+			_, _ = w.Write([]byte("User-agent: KortlinkBot\nDisallow: /private\n\nUser-agent: *\nAllow: /"))
+		}, "/private/page", false},
+		{"other path disallowed", func(w http.ResponseWriter) {
+			_, _ = w.Write([]byte("User-agent: KortlinkBot\nDisallow: /private"))
+		}, "/page", true},
+		{"only other bots disallowed", func(w http.ResponseWriter) {
+			_, _ = w.Write([]byte("User-agent: OtherBot\nDisallow: /"))
+		}, "/page", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched bool
+			server := newSite(t, tt.robots, func(w http.ResponseWriter, r *http.Request) {
+				fetched = true
+				htmlPage("<title>Page</title>")(w, r)
+			})
+			_, err := newFetcher(allowServers(t, server)).Fetch(context.Background(), server.URL+tt.path)
+			if tt.allowed && err != nil {
+				t.Fatalf("Fetch returned %v, want the page", err)
+			}
+			if !tt.allowed {
+				if !errors.Is(err, ErrDisallowedByRobots) {
+					t.Fatalf("Fetch returned %v, want ErrDisallowedByRobots", err)
+				}
+				if fetched {
+					t.Fatal("page was fetched although robots.txt disallows it")
+				}
+			}
+		})
+	}
+}
+
+func TestRobotsAllow(t *testing.T) {
+	robots := `# comment
+User-agent: *
+Disallow: /
+
+User-agent: kortlinkbot
+User-agent: otherbot
+Disallow: /private
+Allow: /private/public
+Disallow: /search*
+`
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"/", true},
+		{"/page", true},
+		{"/private", false},
+		{"/private/page", false},
+		{"/private/public/page", true},
+		{"/search?q=x", false},
+	}
+	for _, tt := range tests {
+		if got := robotsAllow(strings.NewReader(robots), robotsName, tt.path); got != tt.allowed {
+			t.Errorf("robotsAllow(%q) = %v, want %v", tt.path, got, tt.allowed)
+		}
+	}
+}
+
+func TestFetchParsesMetadata(t *testing.T) {
+	server := newSite(t, noRobots, htmlPage(`<!DOCTYPE html>
+<html><head>
+<title>  Plain
+   title </title>
+<meta name="description" content="Plain description">
+<meta property="og:title" content="OG title">
+<meta property="og:image" content="/images/card.png">
+<meta property="og:image" content="/images/second.png">
+</head>
+<body><meta property="og:description" content="Ignored, in body"></body></html>`))
+
+	meta, err := newFetcher(allowServers(t, server)).Fetch(context.Background(), server.URL+"/articles/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{Title: "OG title", Description: "Plain description", Image: server.URL + "/images/card.png"}
+	if *meta != want {
+		t.Fatalf("Fetch = %+v, want %+v", *meta, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/a/b")
+	tests := []struct {
+		name string
+		html string
+		want Metadata
+	}{
+		{
+			"plain title",
+			"<html><head><title>Hello\n  world</title></head></html>",
+			Metadata{Title: "Hello world"},
+		},
+		{
+			"og description wins",
+			`<meta name="description" content="plain"><meta property="og:description" content="og">`,
+			Metadata{Description: "og"},
+		},
+		{
+			"relative image",
+			`<meta property="og:image" content="img.png">`,
+			Metadata{Image: "https://example.com/a/img.png"},
+		},
+		{
+			"non-http image",
+			`<meta property="og:image" content="javascript:alert(1)">`,
+			Metadata{},
+		},
+		{
+			"long title",
+			"<title>" + strings.Repeat("é", maxTitleLength+10) + "</title>",
+			Metadata{Title: strings.Repeat("é", maxTitleLength)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parse(strings.NewReader(tt.html), base); *got != tt.want {
+				t.Fatalf("parse = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchReadsOnlyTheStartOfThePage(t *testing.T) {
+	padding := "<!--" + strings.Repeat("x", maxPageBytes) + "-->"
+	server := newSite(t, noRobots, htmlPage("<html><head>"+padding+"<title>Too late</title></head></html>"))
+
+	meta, err := newFetcher(allowServers(t, server)).Fetch(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "" {
+		t.Fatalf("Title = %q, want nothing read past %d bytes", meta.Title, maxPageBytes)
+	}
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	server := newSite(t, noRobots, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	})
+	if _, err := newFetcher(allowServers(t, server)).Fetch(context.Background(), server.URL+"/file.pdf"); !errors.Is(err, ErrNotHTML) {
+		t.Fatalf("Fetch returned %v, want ErrNotHTML", err)
+	}
+}
+
+func TestFetchImage(t *testing.T) {
+	encode := func(width, height int) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	images := map[string][]byte{
+		"/small.png": encode(16, 8),
+		"/huge.png":  encode(4096, 4096),
+		"/big.png":   bytes.Repeat([]byte{0}, maxImageBytes+1),
+	}
+	server := newSite(t, noRobots, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(images[r.URL.Path])
+	})
+	f := newFetcher(allowServers(t, server))
+
+	img, err := f.FetchImage(context.Background(), server.URL+"/small.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 16 || size.Y != 8 {
+		t.Fatalf("image size = %v, want 16x8", size)
+	}
+	for _, path := range []string{"/huge.png", "/big.png"} {
+		if _, err := f.FetchImage(context.Background(), server.URL+path); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("FetchImage(%s) returned %v, want ErrImageTooLarge", path, err)
+		}
+	}
+}
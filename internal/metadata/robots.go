@@ -0,0 +1,108 @@
+package metadata
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// robotsAllowed checks u against the site's robots.txt. A missing file (any
+// 4xx) allows everything; an unreachable one (5xx or a network error) is
+// treated as disallowing everything, as RFC 9309 asks.
+func (f *Fetcher) robotsAllowed(ctx context.Context, u *url.URL) (bool, error) {
+	robotsURL := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	resp, err := f.get(ctx, robotsURL.String(), "text/plain")
+	if err != nil {
+		return false, fmt.Errorf("could not fetch robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return false, nil
+	case resp.StatusCode >= 400:
+		return true, nil
+	case resp.StatusCode != http.StatusOK:
+		return true, nil
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return robotsAllow(io.LimitReader(resp.Body, maxRobotsBytes), robotsName, path), nil
+}
+
+type robotsRule struct {
+	allow  bool
+	prefix string
+}
+
+// robotsAllow applies the group for agent, or the * group when there is
+// none, to path. The longest matching rule wins and Allow wins ties.
+// Wildcards are not supported: a '*' or '$' ends the prefix, which makes
+// such rules broader than written rather than letting them be ignored.
+func robotsAllow(r io.Reader, agent, path string) bool {
+	var named, wildcard []robotsRule
+	var current []*[]robotsRule
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "user-agent":
+			if !inAgents {
+				current = nil
+				inAgents = true
+			}
+			name := strings.ToLower(value)
+			if name == "*" {
+				current = append(current, &wildcard)
+			} else if strings.HasPrefix(agent, name) {
+				current = append(current, &named)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if i := strings.IndexAny(value, "*$"); i >= 0 {
+				value = value[:i]
+			}
+			if field == "disallow" && value == "" {
+				continue
+			}
+			for _, group := range current {
+				*group = append(*group, robotsRule{allow: field == "allow", prefix: value})
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	rules := wildcard
+	if named != nil {
+		rules = named
+	}
+	best := robotsRule{allow: true}
+	for _, rule := range rules {
+		if !strings.HasPrefix(path, rule.prefix) {
+			continue
+		}
+		if len(rule.prefix) > len(best.prefix) || (len(rule.prefix) == len(best.prefix) && rule.allow) {
+			best = rule
+		}
+	}
+	return best.allow
+}
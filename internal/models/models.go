@@ -3,16 +3,448 @@ package models
 import "time"
 
 type ShortURL struct {
-	ID          string    `json:"id"`
-	OriginalURL string    `json:"original_url"`
-	ShortURL    string    `json:"short_url"`
+	ID                string    `json:"id"`
+	OriginalURL       string    `json:"original_url"`
+	ShortURL          string    `json:"short_url"`
+	Version           int       `json:"version"`
+	AccessCount       int       `json:"access_count"`
+	Disabled          bool      `json:"disabled"`
+	DisabledReason    string    `json:"disabled_reason,omitempty"`
+	PasswordProtected bool      `json:"password_protected"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// RedirectType is the HTTP status used for the redirect: 301, 302
+	// (default), 307 or 308.
+	RedirectType int `json:"redirect_type,omitempty"`
+
+	// UTM holds the utm_* parameters of OriginalURL, kept in their own
+	// columns so stats can be grouped by campaign.
+	UTM
+
+	// Metadata is read from the OriginalURL page in the background after
+	// the link is created or its destination changes.
+	Metadata
+	MetadataFetchedAt *time.Time `json:"metadata_fetched_at,omitempty"`
+
+	// OpenGraph overrides the card link preview crawlers show for the link.
+	OpenGraph *OpenGraph `json:"open_graph,omitempty"`
+
+	// Passthrough controls whether the visitor's query string and any path
+	// after the slug are carried over to the destination.
+	Passthrough *Passthrough `json:"passthrough,omitempty"`
+
+	// Rules send visitors to a different destination when a CEL condition
+	// over the request holds. They are checked in order, before Targeting.
+	Rules []RedirectRule `json:"rules,omitempty"`
+
+	// Targeting sends visitors to a different destination based on their
+	// user agent or location. Rules are checked in order and OriginalURL is
+	// the default.
+	Targeting []TargetingRule `json:"targeting,omitempty"`
+
+	// Split distributes visitors that no targeting rule matches across
+	// weighted variants instead of sending them to OriginalURL.
+	Split *Split `json:"split,omitempty"`
+
+	// Rotation cycles visitors that no targeting rule matches through a
+	// list of destinations. It cannot be combined with Split.
+	Rotation *Rotation `json:"rotation,omitempty"`
+
+	// Tags label the link for grouping and filtering. They are lower-case
+	// and created on first use. On update, omitting tags keeps them and an
+	// empty list removes them.
+	Tags []string `json:"tags,omitempty"`
+
+	// Notes is free text for the link's owners, included in search. On
+	// update, omitting it keeps the current notes and "" clears them.
+	Notes *string `json:"notes,omitempty"`
+
+	// DeletedAt is set while the link is in the trash, and PurgeAt is when
+	// it will be removed for good. Both are only shown in the trash listing.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+
+	// FolderID files the link in a folder. On update, 0 removes it from its
+	// folder.
+	FolderID *int `json:"folder_id,omitempty"`
+
+	// Interstitial shows a page with the destination before every redirect.
+	// On update, omitting it keeps the current setting.
+	Interstitial *bool `json:"interstitial,omitempty"`
+
+	// MaxClicks limits how many redirects the link serves; nil means
+	// unlimited. On update, 0 removes the limit.
+	MaxClicks *int `json:"max_clicks,omitempty"`
+
+	// ActivateAt and DeactivateAt bound when the link redirects. Outside the
+	// window visitors are sent to InactiveURL, or get 404 (not yet active)
+	// or 410 (expired) when it is empty.
+	ActivateAt   *time.Time `json:"activate_at,omitempty"`
+	DeactivateAt *time.Time `json:"deactivate_at,omitempty"`
+	InactiveURL  string     `json:"inactive_url,omitempty"`
+
+	// Password is write-only: set it on create/update to protect the link,
+	// or send an empty string on update to remove protection.
+	Password     *string `json:"password,omitempty"`
+	PasswordHash string  `json:"-"`
+}
+
+type ShortURLPayload struct {
+	OriginalURL  string `json:"original_url" binding:"required"`
+	Password     string `json:"password,omitempty"`
+	RedirectType int    `json:"redirect_type,omitempty" enums:"301,302,307,308"`
+	UTM
+	Passthrough  *Passthrough    `json:"passthrough,omitempty"`
+	Rules        []RedirectRule  `json:"rules,omitempty"`
+	Targeting    []TargetingRule `json:"targeting,omitempty"`
+	Split        *Split          `json:"split,omitempty"`
+	Rotation     *Rotation       `json:"rotation,omitempty"`
+	Interstitial bool            `json:"interstitial,omitempty"`
+	OpenGraph    *OpenGraph      `json:"open_graph,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	FolderID     int             `json:"folder_id,omitempty"`
+	Notes        string          `json:"notes,omitempty"`
+	MaxClicks    int             `json:"max_clicks,omitempty"`
+	ActivateAt   *time.Time      `json:"activate_at,omitempty"`
+	DeactivateAt *time.Time      `json:"deactivate_at,omitempty"`
+	InactiveURL  string          `json:"inactive_url,omitempty"`
+}
+
+type UTM struct {
+	UTMSource   string `json:"utm_source,omitempty"`
+	UTMMedium   string `json:"utm_medium,omitempty"`
+	UTMCampaign string `json:"utm_campaign,omitempty"`
+	UTMTerm     string `json:"utm_term,omitempty"`
+	UTMContent  string `json:"utm_content,omitempty"`
+}
+
+// Metadata describes a destination page: its title, description and
+// Open Graph image.
+type Metadata struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+}
+
+// OpenGraph holds the og:title, og:description and og:image served to
+// link preview crawlers. Empty fields fall back to the fetched Metadata.
+type OpenGraph struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// BulkCreateResult reports the outcome of one row of a bulk create: the
+// created link, or the reason the row was rejected. Row is 1-based and, for
+// CSV uploads, does not count the header line.
+type BulkCreateResult struct {
+	Row   int       `json:"row"`
+	Link  *ShortURL `json:"link,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+type BulkCreateResponse struct {
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Results []BulkCreateResult `json:"results"`
+}
+
+// LinkFilter selects links by slug and attributes. Set fields are combined
+// with AND. A FolderID of 0 selects links that are not in a folder. Links
+// have no owner field, so Owner matches the actor recorded in the link's
+// history when it was created. Links in the trash are only selected,
+// exclusively, when Deleted is set.
+type LinkFilter struct {
+	ShortURLs     []string   `json:"short_urls,omitempty"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	UTMCampaign   string     `json:"utm_campaign,omitempty"`
+	Tag           string     `json:"tag,omitempty"`
+	FolderID      *int       `json:"folder_id,omitempty"`
+	Owner         string     `json:"owner,omitempty"`
+	Deleted       bool       `json:"-"`
+}
+
+const (
+	BulkActionDelete  = "delete"
+	BulkActionDisable = "disable"
+	BulkActionEnable  = "enable"
+	BulkActionRepoint = "repoint"
+	BulkActionRetag   = "retag"
+	BulkActionMove    = "move"
+)
+
+// BulkActionRequest applies Action to every link matching the filter.
+// OriginalURL is the new destination for repoint, Tags replace the links'
+// tags for retag and ToFolderID is the folder links are moved to, 0 for
+// none.
+type BulkActionRequest struct {
+	Action string `json:"action" binding:"required" enums:"delete,disable,enable,repoint,retag,move"`
+	LinkFilter
+	OriginalURL string   `json:"original_url,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	ToFolderID  *int     `json:"to_folder_id,omitempty"`
+}
+
+type BulkActionResponse struct {
+	Action    string   `json:"action"`
+	Affected  int      `json:"affected"`
+	ShortURLs []string `json:"short_urls"`
+}
+
+// Tag is a link label with the number of links carrying it and their
+// combined access count.
+type Tag struct {
+	Name        string `json:"name"`
+	Links       int    `json:"links"`
+	AccessCount int    `json:"access_count"`
+}
+
+type TagPayload struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type Folder struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Links       int       `json:"links"`
 	AccessCount int       `json:"access_count"`
 	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-type ShortURLPayload struct {
-	OriginalURL string `json:"original_url" binding:"required"`
+type FolderPayload struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// SearchResult is a link matching a search. Rank orders results, higher
+// first. Highlights holds, for each field that matched, its HTML-escaped
+// text with the matched terms wrapped in <mark> tags.
+type SearchResult struct {
+	ShortURL
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+	Results []SearchResult `json:"results"`
+}
+
+const (
+	LinkChangeCreate   = "create"
+	LinkChangeUpdate   = "update"
+	LinkChangeDelete   = "delete"
+	LinkChangeRestore  = "restore"
+	LinkChangeRollback = "rollback"
+	// LinkChangeDomainPolicy is a link disabled or re-enabled because the
+	// domain policy changed.
+	LinkChangeDomainPolicy = "domain_policy"
+	// LinkChangeTag and LinkChangeFolder are links whose tags or folder
+	// changed because a tag was renamed or deleted, or their folder deleted.
+	LinkChangeTag    = "tag"
+	LinkChangeFolder = "folder"
+)
+
+// LinkState is the configuration of a link as recorded in its history:
+// everything that can be edited, but not counters or fetched metadata. The
+// password hash is kept so a rollback restores protection, and is never
+// returned by the API.
+type LinkState struct {
+	OriginalURL string `json:"original_url,omitempty"`
+	UTM
+	PasswordHash   string          `json:"password_hash,omitempty"`
+	RedirectType   int             `json:"redirect_type,omitempty"`
+	Passthrough    *Passthrough    `json:"passthrough,omitempty"`
+	Rules          []RedirectRule  `json:"rules,omitempty"`
+	Targeting      []TargetingRule `json:"targeting,omitempty"`
+	Split          *Split          `json:"split,omitempty"`
+	Rotation       *Rotation       `json:"rotation,omitempty"`
+	Interstitial   bool            `json:"interstitial,omitempty"`
+	OpenGraph      *OpenGraph      `json:"open_graph,omitempty"`
+	MaxClicks      *int            `json:"max_clicks,omitempty"`
+	ActivateAt     *time.Time      `json:"activate_at,omitempty"`
+	DeactivateAt   *time.Time      `json:"deactivate_at,omitempty"`
+	InactiveURL    string          `json:"inactive_url,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+	FolderID       *int            `json:"folder_id,omitempty"`
+	Notes          string          `json:"notes,omitempty"`
+	Disabled       bool            `json:"disabled,omitempty"`
+	DisabledReason string          `json:"disabled_reason,omitempty"`
+}
+
+// FieldChange is the value of a field before and after a change. Old is
+// null for fields set when the link was created, New for fields cleared.
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// LinkVersion is one entry of a link's history. Action is create, update,
+// delete, restore or rollback, or the bulk action, domain policy change or
+// tag or folder change that touched the link. Actor is who made the
+// change, as reported by the X-Actor header, and IP the address it came
+// from. RolledBackTo is the version a rollback restored.
+type LinkVersion struct {
+	Version      int                    `json:"version"`
+	Action       string                 `json:"action"`
+	Actor        string                 `json:"actor,omitempty"`
+	IP           string                 `json:"ip,omitempty"`
+	Changes      map[string]FieldChange `json:"changes,omitempty"`
+	RolledBackTo *int                   `json:"rolled_back_to,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+
+	ShortURL string    `json:"-"`
+	State    LinkState `json:"-"`
+}
+
+// ValidationErrors maps each rejected field of a request to the reason it
+// was rejected.
+type ValidationErrors struct {
+	Errors map[string]string `json:"errors"`
+}
+
+type RollbackPayload struct {
+	Version int `json:"version" binding:"required"`
+}
+
+type CampaignStats struct {
+	UTMCampaign string `json:"utm_campaign"`
+	UTMSource   string `json:"utm_source"`
+	UTMMedium   string `json:"utm_medium"`
+	Links       int    `json:"links"`
+	AccessCount int    `json:"access_count"`
+}
+
+// RedirectRule sends visitors to URL when Condition, a CEL expression over
+// the request (time, hour, weekday, headers, query, ip, country, continent,
+// os, device, browser), evaluates to true.
+type RedirectRule struct {
+	Condition string `json:"condition" example:"country == 'DE' && hour >= 18"`
+	URL       string `json:"url"`
+}
+
+// TargetingRule matches when every condition that is set matches the
+// visitor's parsed user agent and GeoIP location. Country is an ISO 3166-1
+// alpha-2 code.
+type TargetingRule struct {
+	OS        string `json:"os,omitempty" enums:"ios,android,windows,macos,linux,chromeos,other"`
+	Device    string `json:"device,omitempty" enums:"mobile,tablet,desktop,bot"`
+	Browser   string `json:"browser,omitempty" enums:"chrome,safari,firefox,edge,opera,samsung,other"`
+	Country   string `json:"country,omitempty" example:"DE"`
+	Continent string `json:"continent,omitempty" enums:"AF,AN,AS,EU,NA,OC,SA"`
+	URL       string `json:"url"`
+}
+
+type Split struct {
+	Variants []SplitVariant `json:"variants"`
+	// Sticky keeps returning visitors on the variant they were first
+	// assigned, using a cookie.
+	Sticky bool `json:"sticky"`
+}
+
+// SplitVariant receives Weight out of the sum of all weights of the traffic.
+type SplitVariant struct {
+	Name   string `json:"name" example:"b"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight" example:"50"`
+}
+
+const (
+	RotationRoundRobin = "round_robin"
+	RotationRandom     = "random"
+)
+
+type Rotation struct {
+	// Mode is round_robin (default), which takes the URLs in turn across
+	// all instances, or random.
+	Mode string   `json:"mode,omitempty" enums:"round_robin,random"`
+	URLs []string `json:"urls"`
+}
+
+// ClickSourceQR marks clicks that came from scanning the link's QR code.
+const ClickSourceQR = "qr"
+
+// Click is one recorded redirect. Country is empty when the visitor's IP
+// could not be resolved, Variant when no split variant was picked and
+// Source for ordinary visits.
+type Click struct {
+	Country string
+	Variant string
+	Source  string
+}
+
+type CountryClicks struct {
+	Country string `json:"country"`
+	Clicks  int    `json:"clicks"`
+}
+
+// VariantClicks counts the clicks of a split variant. URL and Weight are
+// empty for variants that have since been removed from the link.
+type VariantClicks struct {
+	Variant string `json:"variant"`
+	URL     string `json:"url,omitempty"`
+	Weight  int    `json:"weight,omitempty"`
+	Clicks  int    `json:"clicks"`
+}
+
+// ShortURLStats is a link together with its click breakdowns.
+type ShortURLStats struct {
+	ShortURL
+	QRScans   int             `json:"qr_scans"`
+	Countries []CountryClicks `json:"countries"`
+	Variants  []VariantClicks `json:"variants,omitempty"`
+}
+
+const (
+	QueryConflictDestination = "destination"
+	QueryConflictRequest     = "request"
+	QueryConflictAppend      = "append"
+)
+
+type Passthrough struct {
+	// Query merges the incoming query parameters into the destination.
+	Query bool `json:"query"`
+	// QueryConflict decides what happens when a parameter is present in both:
+	// keep the destination's value (default), use the request's, or keep both.
+	QueryConflict string `json:"query_conflict,omitempty" enums:"destination,request,append"`
+	// Path appends segments after the slug to the destination path, so
+	// /docs/getting-started goes to <destination>/getting-started.
+	Path bool `json:"path"`
+}
+
+// DisabledReasonDomainPolicy marks links disabled because their destination
+// violates the domain policy.
+const DisabledReasonDomainPolicy = "domain_policy"
+
+// DisabledReasonManual marks links disabled through the bulk API.
+const DisabledReasonManual = "manual"
+
+const (
+	DomainMatchExact    = "exact"
+	DomainMatchWildcard = "wildcard"
+	DomainMatchRegex    = "regex"
+
+	DomainActionAllow = "allow"
+	DomainActionBlock = "block"
+)
+
+type DomainPolicy struct {
+	ID        int       `json:"id"`
+	Pattern   string    `json:"pattern"`
+	MatchType string    `json:"match_type"`
+	Action    string    `json:"action"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DomainPolicyPayload struct {
+	Pattern   string `json:"pattern" binding:"required"`
+	MatchType string `json:"match_type" binding:"required"`
+	Action    string `json:"action" binding:"required"`
+	Note      string `json:"note"`
 }
 
 type Response struct {
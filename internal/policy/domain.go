@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"kortlink/internal/models"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrDomainBlocked    = errors.New("destination domain is blocked")
+	ErrDomainNotAllowed = errors.New("destination domain is not on the allowlist")
+)
+
+type rule struct {
+	entry models.DomainPolicy
+	re    *regexp.Regexp
+}
+
+// DomainPolicy decides whether a destination host may be linked to. Block
+// entries always win; when at least one allow entry exists, hosts must also
+// match one of them.
+type DomainPolicy struct {
+	allow []rule
+	block []rule
+}
+
+func NewDomainPolicy(entries []models.DomainPolicy) (*DomainPolicy, error) {
+	p := &DomainPolicy{}
+	for _, entry := range entries {
+		r, err := compileRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Action == models.DomainActionAllow {
+			p.allow = append(p.allow, r)
+		} else {
+			p.block = append(p.block, r)
+		}
+	}
+	return p, nil
+}
+
+// ValidateEntry checks that a policy entry is well formed before it is stored.
+func ValidateEntry(entry models.DomainPolicy) error {
+	switch entry.Action {
+	case models.DomainActionAllow, models.DomainActionBlock:
+	default:
+		return fmt.Errorf("invalid action %q", entry.Action)
+	}
+	_, err := compileRule(entry)
+	return err
+}
+
+// Check returns an error if the URL's host is not permitted by the policy.
+func (p *DomainPolicy) Check(rawURL string) error {
+	host, err := hostOf(rawURL)
+	if err != nil {
+		return err
+	}
+	for _, r := range p.block {
+		if r.matches(host) {
+			return ErrDomainBlocked
+		}
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, r := range p.allow {
+		if r.matches(host) {
+			return nil
+		}
+	}
+	return ErrDomainNotAllowed
+}
+
+func compileRule(entry models.DomainPolicy) (rule, error) {
+	r := rule{entry: entry}
+	pattern := strings.TrimSpace(entry.Pattern)
+	if pattern == "" {
+		return r, errors.New("pattern is required")
+	}
+	switch entry.MatchType {
+	case models.DomainMatchExact:
+		r.entry.Pattern = normalizeHost(pattern)
+	case models.DomainMatchWildcard:
+		if !strings.HasPrefix(pattern, "*.") || len(pattern) < 3 {
+			return r, fmt.Errorf("wildcard pattern %q must start with \"*.\"", pattern)
+		}
+		r.entry.Pattern = normalizeHost(pattern[1:])
+	case models.DomainMatchRegex:
+		// Patterns must match the whole host, so "example\.com" does not
+		// also match "example.com.evil.net". Hosts are compared in lower
+		// case, so the pattern ignores case too.
+		re, err := regexp.Compile(`(?i)^(?:` + pattern + `)$`)
+		if err != nil {
+			return r, fmt.Errorf("invalid regex %q: %w", pattern, err)
+		}
+		r.re = re
+	default:
+		return r, fmt.Errorf("invalid match type %q", entry.MatchType)
+	}
+	return r, nil
+}
+
+func (r rule) matches(host string) bool {
+	switch r.entry.MatchType {
+	case models.DomainMatchExact:
+		return host == r.entry.Pattern
+	case models.DomainMatchWildcard:
+		// Pattern is stored as ".example.com", so only subdomains match.
+		return strings.HasSuffix(host, r.entry.Pattern)
+	case models.DomainMatchRegex:
+		return r.re.MatchString(host)
+	}
+	return false
+}
+
+func hostOf(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Hostname() == "" {
+		return "", errors.New("destination URL has no host")
+	}
+	return normalizeHost(u.Hostname()), nil
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,208 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Levels maps the error-correction level names accepted by the API to the
+// recovery levels of the encoder: L (7%), M (15%), Q (25%) and H (30%).
+var Levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// logoRatio is the share of the code's width a center logo may cover. At
+// level H a logo this size leaves enough redundancy to decode reliably.
+const logoRatio = 0.2
+
+type Options struct {
+	// Size is the width and height of the image in pixels.
+	Size int
+	// Level is one of the keys of Levels.
+	Level string
+	// Margin is the quiet zone around the code, in modules.
+	Margin     int
+	Foreground color.Color
+	Background color.Color
+	// Logo, when set, is drawn over the center of the code.
+	Logo image.Image
+}
+
+// code is a rendered symbol: modules[y][x] is true for dark modules, and
+// total is the width including the margin on both sides.
+type code struct {
+	modules [][]bool
+	margin  int
+	total   int
+}
+
+func encode(content string, opts Options) (*code, error) {
+	level, ok := Levels[opts.Level]
+	if !ok {
+		return nil, fmt.Errorf("unknown error correction level %q", opts.Level)
+	}
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	q.DisableBorder = true
+	modules := q.Bitmap()
+	return &code{modules: modules, margin: opts.Margin, total: len(modules) + 2*opts.Margin}, nil
+}
+
+func (c *code) dark(x, y int) bool {
+	x -= c.margin
+	y -= c.margin
+	return y >= 0 && y < len(c.modules) && x >= 0 && x < len(c.modules) && c.modules[y][x]
+}
+
+// logoBox returns the pixel rectangle the logo is drawn in, centered and
+// scaled to fit logoRatio of the code while keeping its aspect ratio.
+func logoBox(logo image.Image, size int) image.Rectangle {
+	bounds := logo.Bounds()
+	limit := int(float64(size) * logoRatio)
+	w, h := limit, limit
+	if bounds.Dx() > bounds.Dy() {
+		h = limit * bounds.Dy() / bounds.Dx()
+	} else {
+		w = limit * bounds.Dx() / bounds.Dy()
+	}
+	x, y := (size-w)/2, (size-h)/2
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// PNG renders content as a PNG image.
+func PNG(content string, opts Options) ([]byte, error) {
+	c, err := encode(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, opts.Size, opts.Size))
+	fg := color.RGBAModel.Convert(opts.Foreground)
+	bg := color.RGBAModel.Convert(opts.Background)
+	for y := 0; y < opts.Size; y++ {
+		my := y * c.total / opts.Size
+		for x := 0; x < opts.Size; x++ {
+			if c.dark(x*c.total/opts.Size, my) {
+				img.Set(x, y, fg)
+			} else {
+				img.Set(x, y, bg)
+			}
+		}
+	}
+	if opts.Logo != nil {
+		drawLogo(img, opts.Logo, logoBox(opts.Logo, opts.Size), bg)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawLogo scales logo into box with nearest-neighbour sampling, over a
+// background-coloured pad so dark modules do not show through transparent
+// parts of the logo.
+func drawLogo(img *image.RGBA, logo image.Image, box image.Rectangle, bg color.Color) {
+	pad := box.Dx() / 10
+	padded := box.Inset(-pad)
+	for y := padded.Min.Y; y < padded.Max.Y; y++ {
+		for x := padded.Min.X; x < padded.Max.X; x++ {
+			img.Set(x, y, bg)
+		}
+	}
+
+	bounds := logo.Bounds()
+	for y := 0; y < box.Dy(); y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/box.Dy()
+		for x := 0; x < box.Dx(); x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/box.Dx()
+			src := color.RGBAModel.Convert(logo.At(sx, sy)).(color.RGBA)
+			if src.A == 0 {
+				continue
+			}
+			dst := img.RGBAAt(box.Min.X+x, box.Min.Y+y)
+			img.SetRGBA(box.Min.X+x, box.Min.Y+y, blend(src, dst))
+		}
+	}
+}
+
+// blend composites the premultiplied src over dst.
+func blend(src, dst color.RGBA) color.RGBA {
+	inv := 255 - uint32(src.A)
+	return color.RGBA{
+		R: uint8(uint32(src.R) + uint32(dst.R)*inv/255),
+		G: uint8(uint32(src.G) + uint32(dst.G)*inv/255),
+		B: uint8(uint32(src.B) + uint32(dst.B)*inv/255),
+		A: uint8(uint32(src.A) + uint32(dst.A)*inv/255),
+	}
+}
+
+// SVG renders content as an SVG document. Modules are drawn in module
+// units and scaled by the viewBox, so the output stays sharp at any size.
+func SVG(content string, opts Options) ([]byte, error) {
+	c, err := encode(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, c.total, c.total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d"%s/>`, c.total, c.total, svgFill(opts.Background))
+
+	var path strings.Builder
+	for y := 0; y < c.total; y++ {
+		for x := 0; x < c.total; x++ {
+			if c.dark(x, y) {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	fmt.Fprintf(&buf, `<path d="%s"%s/>`, path.String(), svgFill(opts.Foreground))
+
+	if opts.Logo != nil {
+		// Work in module units: the box is computed for a code c.total
+		// wide, with the logo embedded as a PNG data URI.
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, opts.Logo); err != nil {
+			return nil, err
+		}
+		scale := 1000
+		box := logoBox(opts.Logo, c.total*scale)
+		pad := box.Dx() / 10
+		padded := box.Inset(-pad)
+		fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s"%s/>`,
+			units(padded.Min.X, scale), units(padded.Min.Y, scale), units(padded.Dx(), scale), units(padded.Dy(), scale), svgFill(opts.Background))
+		fmt.Fprintf(&buf, `<image x="%s" y="%s" width="%s" height="%s" href="data:image/png;base64,%s"/>`,
+			units(box.Min.X, scale), units(box.Min.Y, scale), units(box.Dx(), scale), units(box.Dy(), scale),
+			base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+	buf.WriteString(`</svg>`)
+	return buf.Bytes(), nil
+}
+
+func units(v, scale int) string {
+	return fmt.Sprintf("%.3f", float64(v)/float64(scale))
+}
+
+func svgFill(c color.Color) string {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	fill := fmt.Sprintf(` fill="#%02x%02x%02x"`, rgba.R, rgba.G, rgba.B)
+	if rgba.A != 255 {
+		fill += fmt.Sprintf(` fill-opacity="%.3f"`, float64(rgba.A)/255)
+	}
+	return fill
+}
@@ -0,0 +1,149 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+)
+
+const (
+	// MaxExpressionLength bounds the size of a single rule condition.
+	MaxExpressionLength = 1000
+	// costLimit stops runaway expressions, such as large comprehensions,
+	// from holding up a redirect.
+	costLimit = 10000
+	// maxCachedPrograms bounds the program cache; it is cleared when full.
+	maxCachedPrograms = 10000
+)
+
+// Request holds the attributes of a redirect request that conditions can
+// test. Header names are lower-case; Query has the first value of each
+// parameter.
+type Request struct {
+	Time      time.Time
+	Headers   map[string]string
+	Query     map[string]string
+	IP        string
+	Country   string
+	Continent string
+	OS        string
+	Device    string
+	Browser   string
+}
+
+// env declares the variables available to conditions:
+//
+//	time       timestamp  request time (use time.getHours("Europe/Berlin") for local time)
+//	hour       int        hour of day in UTC, 0-23
+//	weekday    int        day of week in UTC, 0 = Sunday
+//	headers    map        request headers, lower-case names
+//	query      map        query parameters
+//	ip         string     client IP
+//	country    string     ISO 3166-1 alpha-2 country code, "" if unknown
+//	continent  string     continent code, "" if unknown
+//	os, device, browser   values from the parsed user agent
+var env = mustEnv()
+
+func mustEnv() *cel.Env {
+	e, err := cel.NewEnv(
+		cel.Variable("time", cel.TimestampType),
+		cel.Variable("hour", cel.IntType),
+		cel.Variable("weekday", cel.IntType),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("query", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("ip", cel.StringType),
+		cel.Variable("country", cel.StringType),
+		cel.Variable("continent", cel.StringType),
+		cel.Variable("os", cel.StringType),
+		cel.Variable("device", cel.StringType),
+		cel.Variable("browser", cel.StringType),
+		ext.Strings(),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Compile parses and type-checks a condition, which must evaluate to a bool.
+func Compile(expression string) (cel.Program, error) {
+	if expression == "" {
+		return nil, errors.New("condition is empty")
+	}
+	if len(expression) > MaxExpressionLength {
+		return nil, fmt.Errorf("condition is longer than %d characters", MaxExpressionLength)
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if !ast.OutputType().IsExactType(cel.BoolType) {
+		return nil, fmt.Errorf("condition must evaluate to a bool, not %s", ast.OutputType())
+	}
+	return env.Program(ast, cel.CostLimit(costLimit))
+}
+
+// Engine evaluates conditions, caching compiled programs by expression so
+// each one is only compiled once per process.
+type Engine struct {
+	mu       sync.RWMutex
+	programs map[string]cel.Program
+}
+
+func NewEngine() *Engine {
+	return &Engine{programs: make(map[string]cel.Program)}
+}
+
+func (e *Engine) program(expression string) (cel.Program, error) {
+	e.mu.RLock()
+	program, ok := e.programs[expression]
+	e.mu.RUnlock()
+	if ok {
+		return program, nil
+	}
+
+	program, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	if len(e.programs) >= maxCachedPrograms {
+		e.programs = make(map[string]cel.Program)
+	}
+	e.programs[expression] = program
+	e.mu.Unlock()
+	return program, nil
+}
+
+// Match reports whether the condition holds for req. Evaluation errors,
+// such as reading a header that was not sent, are returned and should be
+// treated as no match.
+func (e *Engine) Match(expression string, req Request) (bool, error) {
+	program, err := e.program(expression)
+	if err != nil {
+		return false, err
+	}
+	now := req.Time.UTC()
+	out, _, err := program.Eval(map[string]any{
+		"time":      now,
+		"hour":      now.Hour(),
+		"weekday":   int(now.Weekday()),
+		"headers":   req.Headers,
+		"query":     req.Query,
+		"ip":        req.IP,
+		"country":   req.Country,
+		"continent": req.Continent,
+		"os":        req.OS,
+		"device":    req.Device,
+		"browser":   req.Browser,
+	})
+	if err != nil {
+		return false, err
+	}
+	matched, ok := out.Value().(bool)
+	return ok && matched, nil
+}
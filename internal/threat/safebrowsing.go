@@ -0,0 +1,250 @@
+package threat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// The structures below mirror the JSON body of a Safe Browsing v4
+// threatListUpdates:fetch response. Only RAW compression is supported since
+// the files are expected to be produced by an offline sync job.
+type sbUpdateFile struct {
+	ListUpdateResponses []sbListUpdate `json:"listUpdateResponses"`
+}
+
+type sbListUpdate struct {
+	ThreatType      string          `json:"threatType"`
+	ThreatEntryType string          `json:"threatEntryType"`
+	PlatformType    string          `json:"platformType"`
+	ResponseType    string          `json:"responseType"`
+	Additions       []sbThreatEntry `json:"additions"`
+	Removals        []sbThreatEntry `json:"removals"`
+	Checksum        struct {
+		SHA256 string `json:"sha256"`
+	} `json:"checksum"`
+}
+
+type sbThreatEntry struct {
+	CompressionType string `json:"compressionType"`
+	RawHashes       *struct {
+		PrefixSize int    `json:"prefixSize"`
+		RawHashes  string `json:"rawHashes"`
+	} `json:"rawHashes"`
+	RawIndices *struct {
+		Indices []int `json:"indices"`
+	} `json:"rawIndices"`
+}
+
+// hashList holds the hash prefixes of one Safe Browsing list, kept sorted so
+// removal indices from partial updates can be applied.
+type hashList struct {
+	threatType string
+	prefixes   []string
+}
+
+// parseSafeBrowsing applies the list updates in data to lists, keyed by
+// threat/platform/entry type.
+func parseSafeBrowsing(data []byte, lists map[string]*hashList) error {
+	var file sbUpdateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid safe browsing update: %w", err)
+	}
+
+	for _, update := range file.ListUpdateResponses {
+		key := update.ThreatType + "/" + update.PlatformType + "/" + update.ThreatEntryType
+		list, ok := lists[key]
+		if !ok || update.ResponseType == "FULL_UPDATE" {
+			list = &hashList{threatType: update.ThreatType}
+			lists[key] = list
+		}
+
+		for _, removal := range update.Removals {
+			if removal.RawIndices == nil {
+				return fmt.Errorf("%s: unsupported removal compression %q", key, removal.CompressionType)
+			}
+			list.remove(removal.RawIndices.Indices)
+		}
+		for _, addition := range update.Additions {
+			if addition.RawHashes == nil {
+				return fmt.Errorf("%s: unsupported addition compression %q", key, addition.CompressionType)
+			}
+			raw, err := base64.StdEncoding.DecodeString(addition.RawHashes.RawHashes)
+			if err != nil {
+				return fmt.Errorf("%s: invalid raw hashes: %w", key, err)
+			}
+			size := addition.RawHashes.PrefixSize
+			if size < 4 || size > sha256.Size || len(raw)%size != 0 {
+				return fmt.Errorf("%s: invalid prefix size %d", key, size)
+			}
+			for i := 0; i < len(raw); i += size {
+				list.prefixes = append(list.prefixes, string(raw[i:i+size]))
+			}
+		}
+		sort.Strings(list.prefixes)
+
+		if update.Checksum.SHA256 != "" {
+			if err := list.verify(update.Checksum.SHA256); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (l *hashList) remove(indices []int) {
+	drop := make(map[int]bool, len(indices))
+	for _, i := range indices {
+		drop[i] = true
+	}
+	kept := l.prefixes[:0]
+	for i, prefix := range l.prefixes {
+		if !drop[i] {
+			kept = append(kept, prefix)
+		}
+	}
+	l.prefixes = kept
+}
+
+func (l *hashList) verify(expected string) error {
+	want, err := base64.StdEncoding.DecodeString(expected)
+	if err != nil {
+		return fmt.Errorf("invalid checksum: %w", err)
+	}
+	h := sha256.New()
+	for _, prefix := range l.prefixes {
+		h.Write([]byte(prefix))
+	}
+	if !bytes.Equal(h.Sum(nil), want) {
+		return errors.New("checksum mismatch")
+	}
+	return nil
+}
+
+// urlExpressions returns the host-suffix/path-prefix expressions Safe
+// Browsing hashes for a URL.
+func urlExpressions(rawURL string) ([]string, error) {
+	host, path, query, err := canonicalize(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []string{host}
+	if net.ParseIP(host) == nil {
+		parts := strings.Split(host, ".")
+		for i := len(parts) - 5; i < len(parts)-1; i++ {
+			if i > 0 {
+				hosts = append(hosts, strings.Join(parts[i:], "."))
+			}
+		}
+	}
+
+	var paths []string
+	if query != "" {
+		paths = append(paths, path+"?"+query)
+	}
+	paths = append(paths, path)
+	// Prefixes start at the root and add one directory at a time, never
+	// including the final segment, which is covered by the exact path.
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	prefix := "/"
+	for i := 0; i < 4; i++ {
+		if prefix != path {
+			paths = append(paths, prefix)
+		}
+		if i >= len(segments)-1 {
+			break
+		}
+		prefix += segments[i] + "/"
+	}
+
+	var expressions []string
+	for _, h := range hosts {
+		for _, p := range paths {
+			expressions = append(expressions, h+p)
+		}
+	}
+	return expressions, nil
+}
+
+// canonicalize applies a simplified form of Safe Browsing URL
+// canonicalization and returns the host, path and query.
+func canonicalize(rawURL string) (string, string, string, error) {
+	rawURL = strings.NewReplacer("\t", "", "\r", "", "\n", "").Replace(strings.TrimSpace(rawURL))
+	if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+		rawURL = rawURL[:i]
+	}
+	for i := 0; i < 8; i++ {
+		unescaped, err := url.PathUnescape(rawURL)
+		if err != nil || unescaped == rawURL {
+			break
+		}
+		rawURL = unescaped
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	host := strings.ToLower(strings.Trim(u.Hostname(), "."))
+	for strings.Contains(host, "..") {
+		host = strings.ReplaceAll(host, "..", ".")
+	}
+	if host == "" {
+		return "", "", "", errors.New("URL has no host")
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	cleaned := resolveDots(path)
+	return escape(host), escape(cleaned), escape(u.RawQuery), nil
+}
+
+func resolveDots(path string) string {
+	trailing := strings.HasSuffix(path, "/")
+	var out []string
+	for _, seg := range strings.Split(path, "/") {
+		switch seg {
+		case "", ".":
+		case "..":
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, seg)
+		}
+	}
+	result := "/" + strings.Join(out, "/")
+	if trailing && result != "/" {
+		result += "/"
+	}
+	return result
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= 32 || c >= 127 || c == '#' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,217 @@
+package threat
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Match describes why a URL was flagged. Confirmed is false for hits on a
+// Safe Browsing hash prefix shorter than a full hash: prefix lists also
+// match unrelated URLs, so such a hit means the URL is probably, but not
+// certainly, listed. Callers treat both alike and only word them
+// differently.
+type Match struct {
+	ThreatType string `json:"threat_type"`
+	Source     string `json:"source"`
+	Confirmed  bool   `json:"confirmed"`
+}
+
+// lists is an immutable snapshot of everything loaded from disk; refreshes
+// build a new snapshot and swap it in.
+type lists struct {
+	hashPrefixes map[string]Match // raw hash prefix -> match
+	prefixSizes  []int
+	domains      map[string]Match
+	urls         map[string]Match
+}
+
+// Screener checks URLs against threat lists loaded from local files. Files
+// ending in .json are read as Safe Browsing v4 list updates (hash prefixes);
+// anything else is a plain list with one domain or URL per line and '#'
+// comments.
+type Screener struct {
+	paths []string
+
+	mu      sync.RWMutex
+	current *lists
+}
+
+func NewScreener(paths []string) *Screener {
+	return &Screener{paths: paths, current: &lists{}}
+}
+
+// Enabled reports whether any threat list files are configured.
+func (s *Screener) Enabled() bool {
+	return len(s.paths) > 0
+}
+
+// Load reads every configured file and replaces the active lists. On error
+// the previously loaded lists stay in place.
+func (s *Screener) Load() error {
+	next := &lists{
+		hashPrefixes: make(map[string]Match),
+		domains:      make(map[string]Match),
+		urls:         make(map[string]Match),
+	}
+	sizes := make(map[int]bool)
+
+	for _, path := range s.paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read threat list %s: %w", path, err)
+		}
+		source := filepath.Base(path)
+
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			hashLists := make(map[string]*hashList)
+			if err := parseSafeBrowsing(data, hashLists); err != nil {
+				return fmt.Errorf("could not parse threat list %s: %w", path, err)
+			}
+			for _, list := range hashLists {
+				for _, prefix := range list.prefixes {
+					next.hashPrefixes[prefix] = Match{
+						ThreatType: list.threatType,
+						Source:     source,
+						Confirmed:  len(prefix) == sha256.Size,
+					}
+					sizes[len(prefix)] = true
+				}
+			}
+			continue
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			match := Match{ThreatType: "LISTED", Source: source, Confirmed: true}
+			if strings.Contains(line, "/") {
+				if key, ok := urlKey(line); ok {
+					next.urls[key] = match
+				}
+				continue
+			}
+			next.domains[strings.Trim(strings.ToLower(line), ".")] = match
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("could not parse threat list %s: %w", path, err)
+		}
+	}
+
+	for size := range sizes {
+		next.prefixSizes = append(next.prefixSizes, size)
+	}
+
+	s.mu.Lock()
+	s.current = next
+	s.mu.Unlock()
+
+	log.Info().
+		Int("hash_prefixes", len(next.hashPrefixes)).
+		Int("domains", len(next.domains)).
+		Int("urls", len(next.urls)).
+		Msg("Threat lists loaded")
+	return nil
+}
+
+// Start reloads the lists every interval until stop is closed.
+func (s *Screener) Start(interval time.Duration, stop <-chan struct{}) {
+	if !s.Enabled() || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Load(); err != nil {
+					log.Error().Err(err).Msg("Failed to refresh threat lists")
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Check reports whether rawURL matches any loaded threat list. A confirmed
+// match is preferred over a hash prefix hit; see Match.
+func (s *Screener) Check(rawURL string) (*Match, bool) {
+	s.mu.RLock()
+	current := s.current
+	s.mu.RUnlock()
+
+	if key, ok := urlKey(rawURL); ok {
+		if m, found := current.urls[key]; found {
+			return &m, true
+		}
+	}
+
+	if len(current.domains) > 0 {
+		if u, err := url.Parse(rawURL); err == nil {
+			host := strings.Trim(strings.ToLower(u.Hostname()), ".")
+			for host != "" {
+				if m, found := current.domains[host]; found {
+					return &m, true
+				}
+				i := strings.IndexByte(host, '.')
+				if i < 0 {
+					break
+				}
+				host = host[i+1:]
+			}
+		}
+	}
+
+	if len(current.hashPrefixes) > 0 {
+		expressions, err := urlExpressions(rawURL)
+		if err != nil {
+			return nil, false
+		}
+		var prefixHit *Match
+		for _, expr := range expressions {
+			sum := sha256.Sum256([]byte(expr))
+			for _, size := range current.prefixSizes {
+				if m, found := current.hashPrefixes[string(sum[:size])]; found {
+					if m.Confirmed {
+						return &m, true
+					}
+					if prefixHit == nil {
+						prefixHit = &m
+					}
+				}
+			}
+		}
+		if prefixHit != nil {
+			return prefixHit, true
+		}
+	}
+
+	return nil, false
+}
+
+// urlKey normalizes a URL for exact matching against plain URL entries.
+func urlKey(rawURL string) (string, bool) {
+	host, path, query, err := canonicalize(rawURL)
+	if err != nil {
+		return "", false
+	}
+	key := host + path
+	if query != "" {
+		key += "?" + query
+	}
+	return key, true
+}
@@ -0,0 +1,126 @@
+package useragent
+
+import "strings"
+
+const (
+	OSiOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+	OSOther    = "other"
+
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+
+	BrowserChrome  = "chrome"
+	BrowserSafari  = "safari"
+	BrowserFirefox = "firefox"
+	BrowserEdge    = "edge"
+	BrowserOpera   = "opera"
+	BrowserSamsung = "samsung"
+	BrowserOther   = "other"
+)
+
+var (
+	OSes     = []string{OSiOS, OSAndroid, OSWindows, OSMacOS, OSLinux, OSChromeOS, OSOther}
+	Devices  = []string{DeviceMobile, DeviceTablet, DeviceDesktop, DeviceBot}
+	Browsers = []string{BrowserChrome, BrowserSafari, BrowserFirefox, BrowserEdge, BrowserOpera, BrowserSamsung, BrowserOther}
+)
+
+type Info struct {
+	OS      string `json:"os"`
+	Device  string `json:"device"`
+	Browser string `json:"browser"`
+}
+
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "embedly", "curl/", "wget/", "python-requests", "go-http-client"}
+
+// previewCrawlers are the fetchers social networks and chat apps use to
+// build link previews.
+var previewCrawlers = []string{
+	"facebookexternalhit", "facebot", "twitterbot", "slackbot", "slack-imgproxy", "linkedinbot",
+	"discordbot", "telegrambot", "whatsapp", "skypeuripreview", "pinterest", "redditbot",
+	"embedly", "iframely", "mastodon", "bluesky", "cardyb", "vkshare", "google-pagerenderer",
+}
+
+// IsPreviewCrawler reports whether ua belongs to a link preview crawler,
+// such as Slack's or Twitter's.
+func IsPreviewCrawler(ua string) bool {
+	s := strings.ToLower(ua)
+	for _, crawler := range previewCrawlers {
+		if strings.Contains(s, crawler) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse classifies a User-Agent header. It only looks for the well-known
+// tokens needed for redirect targeting and is not a general-purpose parser.
+func Parse(ua string) Info {
+	s := strings.ToLower(ua)
+	info := Info{OS: OSOther, Device: DeviceDesktop, Browser: BrowserOther}
+
+	switch {
+	case strings.Contains(s, "iphone") || strings.Contains(s, "ipod"):
+		info.OS, info.Device = OSiOS, DeviceMobile
+	case strings.Contains(s, "ipad"):
+		info.OS, info.Device = OSiOS, DeviceTablet
+	case strings.Contains(s, "android"):
+		info.OS = OSAndroid
+		// Android tablets omit "mobile" from their user agent.
+		if strings.Contains(s, "mobile") {
+			info.Device = DeviceMobile
+		} else {
+			info.Device = DeviceTablet
+		}
+	case strings.Contains(s, "windows phone"):
+		info.OS, info.Device = OSWindows, DeviceMobile
+	case strings.Contains(s, "windows"):
+		info.OS = OSWindows
+	case strings.Contains(s, "cros"):
+		info.OS = OSChromeOS
+	case strings.Contains(s, "macintosh") || strings.Contains(s, "mac os x"):
+		info.OS = OSMacOS
+		// iPadOS requests desktop sites with a Mac user agent; the "mobile"
+		// token still gives it away.
+		if strings.Contains(s, "mobile/") {
+			info.OS, info.Device = OSiOS, DeviceTablet
+		}
+	case strings.Contains(s, "linux"):
+		info.OS = OSLinux
+	}
+	if info.Device == DeviceDesktop && (strings.Contains(s, "tablet") || strings.Contains(s, "kindle") || strings.Contains(s, "silk/")) {
+		info.Device = DeviceTablet
+	}
+
+	// Order matters: Edge, Opera and Samsung Internet also claim Chrome,
+	// and everything Chromium-based claims Safari.
+	switch {
+	case strings.Contains(s, "edg/") || strings.Contains(s, "edge/") || strings.Contains(s, "edga/") || strings.Contains(s, "edgios/"):
+		info.Browser = BrowserEdge
+	case strings.Contains(s, "opr/") || strings.Contains(s, "opera"):
+		info.Browser = BrowserOpera
+	case strings.Contains(s, "samsungbrowser/"):
+		info.Browser = BrowserSamsung
+	case strings.Contains(s, "firefox/") || strings.Contains(s, "fxios/"):
+		info.Browser = BrowserFirefox
+	case strings.Contains(s, "chrome/") || strings.Contains(s, "crios/") || strings.Contains(s, "chromium/"):
+		info.Browser = BrowserChrome
+	case strings.Contains(s, "safari/"):
+		info.Browser = BrowserSafari
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(s, marker) {
+			info.Device = DeviceBot
+			break
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,86 @@
+package utility
+
+import (
+	"fmt"
+	"kortlink/internal/models"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var utmValuePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._+~-]*$`)
+
+const maxUTMValueLength = 100
+
+// ApplyUTM validates the structured UTM fields, merges them into rawURL and
+// returns the resulting URL together with the utm_* parameters it carries.
+// Values are trimmed and lower-cased, and spaces become underscores, so
+// "Spring Sale" and "spring_sale" end up in the same campaign. When no
+// fields are given the URL is returned unchanged.
+func ApplyUTM(rawURL string, utm models.UTM) (string, models.UTM, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", models.UTM{}, err
+	}
+	query := u.Query()
+
+	fields := utmFields(&utm)
+	provided := false
+	for _, f := range fields {
+		if *f.value == "" {
+			continue
+		}
+		provided = true
+		value := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(*f.value)), " ", "_")
+		if len(value) > maxUTMValueLength || !utmValuePattern.MatchString(value) {
+			return "", models.UTM{}, fmt.Errorf("%s must be up to %d characters of letters, digits, '.', '_', '+', '~' or '-'", f.name, maxUTMValueLength)
+		}
+		if existing := query.Get(f.name); existing != "" && existing != value {
+			return "", models.UTM{}, fmt.Errorf("%s conflicts with the value already in original_url", f.name)
+		}
+		*f.value = value
+	}
+
+	if provided {
+		for _, required := range fields[:3] {
+			if *required.value == "" && query.Get(required.name) == "" {
+				return "", models.UTM{}, fmt.Errorf("%s is required when UTM parameters are set", required.name)
+			}
+		}
+		for _, f := range fields {
+			if *f.value != "" {
+				query.Set(f.name, *f.value)
+			}
+		}
+		u.RawQuery = query.Encode()
+		rawURL = u.String()
+	}
+
+	return rawURL, ExtractUTM(query), nil
+}
+
+// ExtractUTM reads the utm_* parameters from a query.
+func ExtractUTM(query url.Values) models.UTM {
+	var utm models.UTM
+	for _, f := range utmFields(&utm) {
+		*f.value = query.Get(f.name)
+	}
+	return utm
+}
+
+type utmField struct {
+	name  string
+	value *string
+}
+
+// utmFields lists the UTM parameters; source, medium and campaign come first
+// because they are required.
+func utmFields(utm *models.UTM) []utmField {
+	return []utmField{
+		{"utm_source", &utm.UTMSource},
+		{"utm_medium", &utm.UTMMedium},
+		{"utm_campaign", &utm.UTMCampaign},
+		{"utm_term", &utm.UTMTerm},
+		{"utm_content", &utm.UTMContent},
+	}
+}